@@ -8,12 +8,19 @@ import (
 	"path/filepath"
 
 	"github.com/h8liu/dig8/dcrl"
+	"github.com/h8liu/dig8/dns8"
 )
 
 func crawl() {
 	name := flag.String("n", "", "job name")
 	arch := flag.String("a", "", "archive path")
 	db := flag.String("db", "", "database path")
+	maxDepth := flag.Int("depth", dns8.DefaultMaxDepth, "max query tree depth")
+	maxQuery := flag.Int("maxq", dns8.DefaultMaxQuery, "max queries per domain")
+	timeout := flag.Duration("timeout", 0, "time limit per domain, 0 for none")
+	cache := flag.Bool("cache", false, "cache records across the domains")
+	rootZone := flag.String("rootzone", "", "local root zone file")
+	caa := flag.Bool("caa", false, "also collect the caa records")
 	flag.Parse()
 	args := flag.Args()
 
@@ -36,12 +43,29 @@ func crawl() {
 		log.Fatal(e)
 	}
 
+	var root *dns8.RootZone
+	if *rootZone != "" {
+		root, e = dns8.LoadRootZone(*rootZone)
+		if e != nil {
+			log.Fatal(e)
+		}
+		log.Printf("root zone serial %d", root.Serial())
+	}
+
 	j := &dcrl.Job{
 		Name:     jobName,
 		Domains:  doms,
 		Archive:  *arch,
 		DB:       *db,
 		Progress: jobProgress,
+		Cache:    *cache,
+		RootZone: root,
+		CAA:      *caa,
+		Budget: dns8.Budget{
+			MaxDepth: *maxDepth,
+			MaxQuery: *maxQuery,
+			Timeout:  *timeout,
+		},
 	}
 
 	e = j.Do()
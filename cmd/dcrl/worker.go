@@ -36,7 +36,9 @@ func parseDomains(doms []string) ([]*dns8.Domain, error) {
 
 var backoff = time.Second
 
-func work(addr string, i int, archive, logPath string) error {
+func work(addr string, i int, archive, logPath, cacheFile string,
+	qps, sqps float64,
+) error {
 	name := workerName(i)
 
 	c, e := rpc.DialHTTP("tcp", addr)
@@ -70,10 +72,14 @@ func work(addr string, i int, archive, logPath string) error {
 		}
 
 		j := &dcrl.Job{
-			Name:    job.Name,
-			Archive: arch,
-			Domains: doms,
-			Log:     logPath,
+			Name:      job.Name,
+			Archive:   arch,
+			Domains:   doms,
+			Log:       logPath,
+			CacheFile: cacheFile,
+
+			RateLimit:       qps,
+			ServerRateLimit: sqps,
 			Progress: func(p *dcrl.Progress) error {
 				var okay bool
 				e = c.Call("Server.Progress", p, &okay)
@@ -94,9 +100,11 @@ func work(addr string, i int, archive, logPath string) error {
 	}
 }
 
-func workForever(addr string, i int, archive, logPath string) {
+func workForever(addr string, i int, archive, logPath, cacheFile string,
+	qps, sqps float64,
+) {
 	for {
-		e := work(addr, i, archive, logPath)
+		e := work(addr, i, archive, logPath, cacheFile, qps, sqps)
 		if e != nil {
 			log.Print(e)
 		}
@@ -109,12 +117,26 @@ var (
 	workaddr = flag.String("workaddr", "localhost:5300", "server address")
 	archPath = flag.String("arch", "archive", "archive path")
 	logPath  = flag.String("log", "log", "log path")
+	snapshot = flag.String("snapshot", "", "name server cache snapshot file, suffixed by the worker index")
+	qps      = flag.Float64("qps", 0, "queries per second of a worker, 0 for no limit")
+	sqps     = flag.Float64("sqps", 0, "queries per second per server of a worker")
 )
 
+// workerSnapshot returns the snapshot file of a worker. Each worker has
+// its own file, or the concurrent jobs would overwrite each other's.
+func workerSnapshot(path string, i int) string {
+	if path == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
 func worker() {
 	for i := 1; i < *nworker; i++ {
-		go workForever(*workaddr, i, *archPath, *logPath)
+		go workForever(*workaddr, i, *archPath, *logPath,
+			workerSnapshot(*snapshot, i), *qps, *sqps)
 	}
 
-	workForever(*workaddr, *nworker, *archPath, *logPath)
+	workForever(*workaddr, *nworker, *archPath, *logPath,
+		workerSnapshot(*snapshot, *nworker), *qps, *sqps)
 }
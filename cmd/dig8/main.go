@@ -17,8 +17,23 @@ func ne(e error) {
 
 func main() {
 	quiet := flag.Bool("q", false, "quiet")
+	edns := flag.Int("edns", 0, "EDNS0 udp payload size in [512, 65535], 0 for no EDNS0")
+	dual := flag.Bool("6", false, "also reach name servers over IPv6")
+	mix := flag.Bool("0x20", false, "randomize the letter cases of query names")
+	hints := flag.String("hints", "", "root hints file, like named.root")
+	prime := flag.Bool("prime", false, "prime the root servers first")
+	rootZone := flag.String("rootzone", "", "local root zone file")
+	validate := flag.Bool("validate", false, "validate the addresses with DNSSEC")
+	anchors := flag.String("anchors", "", "root trust anchors file, DS records")
+	caa := flag.Bool("caa", false, "also collect the caa records")
 	flag.Parse()
 
+	if *edns != 0 && (*edns < 512 || *edns > 65535) {
+		fmt.Fprintln(os.Stderr, "edns payload size should be in [512, 65535]")
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	c, e := dns8.NewClient()
 	ne(e)
 
@@ -29,6 +44,27 @@ func main() {
 		t.Log = nil
 	}
 	t.Out = os.Stdout
+	if *dual {
+		t.IPPolicy = dns8.IPDual
+	}
+	t.Use0x20 = *mix
+	if *hints != "" {
+		t.Roots, e = dns8.LoadHints(*hints)
+		ne(e)
+	}
+	t.PrimeRoots = *prime
+	if *rootZone != "" {
+		t.RootZone, e = dns8.LoadRootZone(*rootZone)
+		ne(e)
+	}
+	if *anchors != "" {
+		t.Anchors, e = dns8.LoadAnchors(*anchors)
+		ne(e)
+	}
+	if *edns != 0 {
+		t.Edns = dns8.NewEdns()
+		t.Edns.UDPSize = uint16(*edns)
+	}
 
 	args := flag.Args()
 	for _, s := range args {
@@ -39,9 +75,18 @@ func main() {
 		}
 		fmt.Printf("// %v\n", d)
 
-		_, e = t.T(dns8.NewInfo(d))
+		info := dns8.NewInfo(d)
+		info.CAA = *caa
+		_, e = t.T(info)
 		if e != nil {
 			fmt.Fprintln(os.Stderr, e)
 		}
+
+		if *validate {
+			_, e = t.T(dns8.NewValidate(d, dns8.A))
+			if e != nil {
+				fmt.Fprintln(os.Stderr, e)
+			}
+		}
 	}
 }
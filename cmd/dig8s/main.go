@@ -9,8 +9,11 @@ import (
 )
 
 var (
-	arch = flag.String("a", "", "archive path")
-	db = flag.String("db", "", "database path")
+	arch     = flag.String("a", "", "archive path")
+	db       = flag.String("db", "", "database path")
+	qps      = flag.Float64("qps", 0, "queries per second, 0 for no limit")
+	sqps     = flag.Float64("sqps", 0, "queries per second per server")
+	snapshot = flag.String("snapshot", "", "name server cache snapshot file")
 )
 
 func main() {
@@ -31,11 +34,14 @@ func main() {
 	}
 
 	j := &dcrl.Job{
-		Name:     jobName,
-		Domains:  doms,
-		Archive:  *arch,
-		DB:       *db,
-		Progress: func (p *dcrl.Progress) error {
+		Name:            jobName,
+		Domains:         doms,
+		Archive:         *arch,
+		DB:              *db,
+		RateLimit:       *qps,
+		ServerRateLimit: *sqps,
+		CacheFile:       *snapshot,
+		Progress: func(p *dcrl.Progress) error {
 			log.Println(p.String())
 			return nil
 		},
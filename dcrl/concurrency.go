@@ -0,0 +1,80 @@
+package dcrl
+
+import (
+	"github.com/h8liu/dig8/dns8"
+)
+
+// Concurrency defaults
+const (
+	DefaultMinConcurrency = 20
+	DefaultMaxConcurrency = 2000
+
+	nquota = 300 // initial concurrency
+)
+
+// AIMD parameters of the concurrency controller
+const (
+	concurrencyStep = 20   // additive increase
+	lowTimeoutRate  = 0.05 // grows when timeout rate is under this
+	highTimeoutRate = 0.2  // backs off when timeout rate is over this
+	highIDPressure  = 0.5  // backs off when id pool pressure is over this
+	minSamples      = 50   // queries needed in a period to judge
+)
+
+// concurrency is an AIMD controller for the number of domains that
+// are crawled at the same time. It grows the concurrency while the
+// timeout rate of the client stays low, and halves it when timeouts
+// or id pool pressure rise.
+type concurrency struct {
+	min, max int
+	cur      int
+	last     dns8.ClientStats
+}
+
+func newConcurrency(min, max int) *concurrency {
+	if min <= 0 {
+		min = DefaultMinConcurrency
+	}
+	if max <= 0 {
+		max = DefaultMaxConcurrency
+	}
+	if max < min {
+		max = min
+	}
+
+	ret := &concurrency{min: min, max: max, cur: nquota}
+	ret.clamp()
+	return ret
+}
+
+func (c *concurrency) clamp() {
+	if c.cur < c.min {
+		c.cur = c.min
+	}
+	if c.cur > c.max {
+		c.cur = c.max
+	}
+}
+
+// update updates the concurrency with the client stats of the last
+// period, and returns the new concurrency.
+func (c *concurrency) update(stats dns8.ClientStats) int {
+	sent := stats.Sent - c.last.Sent
+	timeouts := stats.Timeouts - c.last.Timeouts
+
+	switch {
+	case stats.IDPressure > highIDPressure:
+		c.cur /= 2
+	case sent < minSamples:
+		// not enough queries to judge, keep the last stats
+		return c.cur
+	case float64(timeouts) > highTimeoutRate*float64(sent):
+		c.cur /= 2
+	case float64(timeouts) < lowTimeoutRate*float64(sent):
+		c.cur += concurrencyStep
+	}
+
+	c.last = stats
+	c.clamp()
+	return c.cur
+}
@@ -0,0 +1,41 @@
+package dcrl
+
+import (
+	"testing"
+
+	"github.com/h8liu/dig8/dns8"
+)
+
+func TestConcurrency(t *testing.T) {
+	c := newConcurrency(20, 1000)
+	if c.cur != nquota {
+		t.Fatalf("expect %d to start, got %d", nquota, c.cur)
+	}
+
+	var stats dns8.ClientStats
+	period := func(sent, timeouts uint64, pressure float64) int {
+		stats.Sent += sent
+		stats.Timeouts += timeouts
+		stats.IDPressure = pressure
+		return c.update(stats)
+	}
+
+	for i, test := range []struct {
+		sent, timeouts uint64
+		pressure       float64
+		expect         int
+	}{
+		{100, 0, 0, 320},    // low timeouts, grows
+		{100, 10, 0, 320},   // in between, holds
+		{100, 50, 0, 160},   // high timeouts, halves
+		{10, 10, 0, 160},    // too few samples to judge
+		{100, 0, 0.9, 80},   // high id pressure, halves
+		{10, 0, 0.9, 40},    // id pressure needs no samples
+		{100, 90, 0, 20},    // halves to the min
+		{100, 100, 0.9, 20}, // stays at the min
+	} {
+		if got := period(test.sent, test.timeouts, test.pressure); got != test.expect {
+			t.Errorf("period %d: expect %d, got %d", i, test.expect, got)
+		}
+	}
+}
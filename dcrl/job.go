@@ -1,11 +1,13 @@
 package dcrl
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3" // sqlite3
@@ -48,8 +50,41 @@ type Job struct {
 
 	Progress func(p *Progress) error // progress report function
 
-	db     *sql.DB
-	closed chan struct{}
+	// Query rate limits of the client, 0 for no limit
+	RateLimit       float64 // queries per second
+	ServerRateLimit float64 // queries per second for each server
+
+	// Bounds of the number of domains crawled at the same time,
+	// 0 for the defaults. The concurrency adapts to the timeout
+	// rate within the bounds.
+	MinConcurrency int
+	MaxConcurrency int
+
+	// Budget limits the work for each domain, so that a pathological
+	// domain does not hold a slot for long.
+	Budget dns8.Budget
+
+	// Cache shares a record cache among the domains, which saves
+	// the queries for the delegations and the name servers.
+	Cache bool
+
+	// CacheFile is a snapshot of the name server cache. When set, the
+	// cache is restored from it before crawling, and saved back to it
+	// from time to time, so that the cache is warm after restarts.
+	CacheFile string
+
+	// RootZone is a local root zone shared by the domains, so that
+	// the referrals from the root are answered without queries.
+	RootZone *dns8.RootZone
+
+	// CAA also collects the CAA records of the registered domains.
+	CAA bool
+
+	db          *sql.DB
+	cache       *dns8.Cache
+	rrCache     *dns8.RRCache
+	throttle    *throttle
+	concurrency *concurrency
 }
 
 func (j *Job) reportProg(p *Progress) error {
@@ -64,6 +99,7 @@ func (j *Job) prog(crawled int) error {
 	ret.Name = j.Name
 	ret.Total = len(j.Domains)
 	ret.Crawled = crawled
+	ret.Concurrency = j.throttle.getLimit()
 
 	return j.reportProg(ret)
 }
@@ -170,48 +206,53 @@ func (j *Job) createDB() error {
 	return nil
 }
 
-const nquota = 300
-
-func makeQuotas() chan int {
-	ret := make(chan int, nquota)
-	for i := 0; i < nquota; i++ {
-		ret <- i
-	}
-	return ret
-}
-
-func (j *Job) launch(c *dns8.Client, finished chan *task) {
-	quotas := makeQuotas()
+func (j *Job) launch(ctx context.Context, c *dns8.Client,
+	finished chan *task, wg *sync.WaitGroup,
+) {
+	defer wg.Done()
 
 	for i, d := range j.Domains {
-		if len(j.closed) > 0 {
+		j.throttle.acquire()
+		if ctx.Err() != nil {
+			j.throttle.release()
 			break
 		}
 
-		quota := <-quotas
 		t := &task{
 			domain: d,
 			client: c,
+			budget: j.Budget,
+			cache:  j.cache,
+			rrs:    j.rrCache,
+			root:   j.RootZone,
+			caa:    j.CAA,
 			id:     i,
 		}
 
-		go func(t *task, q int) {
-			t.run()
-			if len(j.closed) == 0 {
-				finished <- t
+		wg.Add(1)
+		go func(t *task) {
+			defer wg.Done()
+			defer j.throttle.release()
+
+			t.run(ctx)
+			select {
+			case finished <- t:
+			case <-ctx.Done():
 			}
-			quotas <- q
-		}(t, quota)
+		}(t)
 	}
 }
 
-func (j *Job) crawl() error {
+func (j *Job) crawl(ctx context.Context) error {
 	c, e := dns8.NewClient()
 	if e != nil {
 		return e
 	}
 	defer c.Close()
 
+	c.RateLimit = j.RateLimit
+	c.ServerRateLimit = j.ServerRateLimit
+
 	finished := make(chan *task, nquota)
 
 	ins, err := newTaskInserter(j.db)
@@ -219,15 +260,42 @@ func (j *Job) crawl() error {
 		return err
 	}
 
-	j.closed = make(chan struct{}, 1)
-	defer func() { j.closed <- struct{}{} }()
+	// on return, stops the tasks and waits for them before the
+	// client is closed
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	j.cache = dns8.NewCache() // isolated from other jobs
+	if j.CacheFile != "" {
+		j.loadCache()
+		defer j.saveCache()
+	}
+	if j.Cache {
+		j.rrCache = dns8.NewRRCache()
+	}
+	j.concurrency = newConcurrency(j.MinConcurrency, j.MaxConcurrency)
+	j.throttle = newThrottle(j.concurrency.cur)
 
-	go j.launch(c, finished)
+	wg.Add(1)
+	go j.launch(ctx, c, finished, &wg)
 
 	ticker := time.Tick(time.Second * 3)
+	adjust := time.Tick(time.Second)
+	snapshot := time.Tick(time.Minute)
 	n := 0
 	for n < len(j.Domains) {
 		select {
+		case <-ctx.Done():
+			ins.Close()
+			return ctx.Err()
+		case <-adjust:
+			j.throttle.setLimit(j.concurrency.update(c.Stats()))
+		case <-snapshot:
+			if j.CacheFile != "" {
+				j.saveCache()
+			}
 		case <-ticker:
 			err = j.prog(n)
 			if err != nil {
@@ -240,6 +308,11 @@ func (j *Job) crawl() error {
 				ins.Close()
 				return err
 			}
+
+			j.cache.Clean()
+			if j.rrCache != nil {
+				j.rrCache.Clean()
+			}
 		case t := <-finished:
 			err = ins.Insert(t)
 			if err != nil {
@@ -254,6 +327,25 @@ func (j *Job) crawl() error {
 	return j.prog(n)
 }
 
+// loadCache restores the name server cache from the snapshot file.
+// A missing or broken snapshot only means a cold start.
+func (j *Job) loadCache() {
+	n, e := j.cache.LoadFile(j.CacheFile)
+	if e != nil {
+		if !os.IsNotExist(e) {
+			log.Printf("[%s] load cache: %v", j.Name, e)
+		}
+		return
+	}
+	log.Printf("[%s] %d zones restored from cache", j.Name, n)
+}
+
+func (j *Job) saveCache() {
+	if e := j.cache.SaveFile(j.CacheFile); e != nil {
+		log.Printf("[%s] save cache: %v", j.Name, e)
+	}
+}
+
 func (j *Job) writeOut() error {
 	var outPath = j.Name
 
@@ -301,6 +393,12 @@ func (j *Job) writeOut() error {
 
 // Do performs the job.
 func (j *Job) Do() error {
+	return j.DoContext(context.Background())
+}
+
+// DoContext performs the job. When ctx is canceled, the crawling stops,
+// and the job returns with the context error.
+func (j *Job) DoContext(ctx context.Context) error {
 	if !ValidJobName(j.Name) {
 		return fmt.Errorf("invalid job name %q", j.Name)
 	}
@@ -320,7 +418,7 @@ func (j *Job) Do() error {
 	}
 
 	log.Printf("[%s] start crawling", j.Name)
-	e = j.crawl()
+	e = j.crawl(ctx)
 	if e != nil {
 		j.errProg(e)
 		return e
@@ -12,6 +12,8 @@ type Progress struct {
 	Total   int
 	Done    bool
 	Error   string
+
+	Concurrency int // number of domains crawled at the same time
 }
 
 func (p *Progress) String() string {
@@ -24,6 +26,9 @@ func (p *Progress) String() string {
 		fmt.Fprintf(buf, "done (%d domains)", p.Total)
 	} else {
 		fmt.Fprintf(buf, "%d/%d", p.Crawled, p.Total)
+		if p.Concurrency > 0 {
+			fmt.Fprintf(buf, " (concurrency %d)", p.Concurrency)
+		}
 	}
 
 	return buf.String()
@@ -2,6 +2,7 @@ package dcrl
 
 import (
 	"bytes"
+	"context"
 
 	"github.com/h8liu/dig8/dns8"
 )
@@ -10,6 +11,11 @@ import (
 type task struct {
 	domain *dns8.Domain
 	client *dns8.Client
+	budget dns8.Budget
+	cache  *dns8.Cache
+	rrs    *dns8.RRCache
+	root   *dns8.RootZone
+	caa    bool
 	id     int
 
 	res string // result
@@ -18,13 +24,18 @@ type task struct {
 	err string // error
 }
 
-func (t *task) run() {
+func (t *task) run(ctx context.Context) {
 	logBuf := new(bytes.Buffer)
 	tm := dns8.NewTerm(t.client)
 	tm.Log = logBuf
+	tm.Budget = t.budget
+	tm.Cache = t.cache
+	tm.RRCache = t.rrs
+	tm.RootZone = t.root
 
 	info := dns8.NewInfo(t.domain)
-	_, err := tm.T(info)
+	info.CAA = t.caa
+	_, err := tm.TContext(ctx, info)
 
 	if err == nil {
 		t.out = info.Out()
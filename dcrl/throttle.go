@@ -0,0 +1,50 @@
+package dcrl
+
+import (
+	"sync"
+)
+
+// throttle limits the number of running tasks, and the limit can be
+// changed while tasks are running.
+type throttle struct {
+	lock    sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	running int
+}
+
+func newThrottle(limit int) *throttle {
+	ret := new(throttle)
+	ret.cond = sync.NewCond(&ret.lock)
+	ret.limit = limit
+	return ret
+}
+
+func (t *throttle) acquire() {
+	t.lock.Lock()
+	for t.running >= t.limit {
+		t.cond.Wait()
+	}
+	t.running++
+	t.lock.Unlock()
+}
+
+func (t *throttle) release() {
+	t.lock.Lock()
+	t.running--
+	t.lock.Unlock()
+	t.cond.Signal()
+}
+
+func (t *throttle) setLimit(n int) {
+	t.lock.Lock()
+	t.limit = n
+	t.lock.Unlock()
+	t.cond.Broadcast()
+}
+
+func (t *throttle) getLimit() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.limit
+}
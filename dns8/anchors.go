@@ -0,0 +1,89 @@
+package dns8
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// RootAnchors returns the DS records of the root key signing keys
+// published by IANA, KSK-2017 and KSK-2024, as the trust anchors for
+// DNSSEC validation. See data.iana.org/root-anchors for reference.
+func RootAnchors() []*RdDs {
+	ds := func(tag uint16, h string) *RdDs {
+		digest, e := hex.DecodeString(h)
+		bugOn(e != nil)
+		return &RdDs{
+			KeyTag:     tag,
+			Algorithm:  AlgRSASHA256,
+			DigestType: DigestSHA256,
+			Digest:     digest,
+		}
+	}
+
+	return []*RdDs{
+		ds(20326, "e06d44b80b8f1d39a95c0b0d7c65d08458e880409bbc683457104237c7f8ec8d"),
+		ds(38696, "683d2d0acb8c9b712a1948b27f741219298d0a450d612c483af444a4c0fb2b16"),
+	}
+}
+
+// ParseAnchors parses the DS records of the root in the zone file
+// format, as the trust anchors. Other records are skipped.
+func ParseAnchors(r io.Reader) ([]*RdDs, error) {
+	var ret []*RdDs
+	e := readZone(r, func(l *zoneLine) error {
+		if l.typ != "ds" || !l.owner.IsRoot() {
+			return nil
+		}
+		if len(l.rdata) < 4 {
+			return l.errorf("invalid ds")
+		}
+
+		var nums [3]uint64
+		for i, bits := range []int{16, 8, 8} {
+			n, e := strconv.ParseUint(l.rdata[i], 10, bits)
+			if e != nil {
+				return l.errorf("invalid ds field %q", l.rdata[i])
+			}
+			nums[i] = n
+		}
+
+		var h string
+		for _, s := range l.rdata[3:] {
+			h += s
+		}
+		digest, e := hex.DecodeString(h)
+		if e != nil {
+			return l.errorf("invalid ds digest")
+		}
+
+		ret = append(ret, &RdDs{
+			KeyTag:     uint16(nums[0]),
+			Algorithm:  uint8(nums[1]),
+			DigestType: uint8(nums[2]),
+			Digest:     digest,
+		})
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no ds of the root")
+	}
+	return ret, nil
+}
+
+// LoadAnchors loads the trust anchors from a file.
+func LoadAnchors(path string) ([]*RdDs, error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+
+	return ParseAnchors(f)
+}
@@ -1,5 +1,9 @@
 package dns8
 
+import (
+	"context"
+)
+
 // Branch is a branch in a query tree
 type Branch struct {
 	Task     // the task binded
@@ -30,8 +34,14 @@ func (br *Branch) IsLeaf() bool { return false }
 type Cursor interface {
 	P() *Printer
 	E() error
+	Config() *TermConfig
+	Context() context.Context
 	T(t Task) (*Branch, error)
 	Q(q *Query) (*Leaf, error)
+
+	// Ts runs sibling tasks concurrently, but keeps the branches and
+	// the printed logs in the order of the tasks.
+	Ts(ts ...Task) ([]*Branch, error)
 }
 
 // Task is an executable node that builds a query tree
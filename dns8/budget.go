@@ -0,0 +1,47 @@
+package dns8
+
+import (
+	"errors"
+	"time"
+)
+
+// Default query tree limits
+const (
+	DefaultMaxDepth = 30
+	DefaultMaxQuery = 500
+)
+
+// Budget limits the work of a top-level task. When any budget is
+// exhausted, the task stops with ErrTooDeep, ErrTooManyQueries or
+// ErrTimeBudget, and the partial query tree is kept.
+type Budget struct {
+	MaxDepth int           // max depth of the query tree, 0 for default
+	MaxQuery int           // max number of queries, 0 for default
+	Timeout  time.Duration // wall-clock time limit, 0 for no limit
+}
+
+// Errors of exhausted budgets
+var (
+	ErrTooDeep        = errors.New("too deep")
+	ErrTooManyQueries = errors.New("too many queries")
+	ErrTimeBudget     = errors.New("time budget exceeded")
+)
+
+// IsBudgetError checks if e is an error of an exhausted budget.
+func IsBudgetError(e error) bool {
+	return e == ErrTooDeep || e == ErrTooManyQueries || e == ErrTimeBudget
+}
+
+func (b *Budget) maxDepth() int {
+	if b.MaxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return b.MaxDepth
+}
+
+func (b *Budget) maxQuery() int {
+	if b.MaxQuery <= 0 {
+		return DefaultMaxQuery
+	}
+	return b.MaxQuery
+}
@@ -1,59 +1,75 @@
 package dns8
 
 import (
-	"time"
+	"container/list"
+	"sync"
 )
 
-// Cache is a name server cache
-type Cache struct {
-	RegistrarOnly bool
-	entries       map[string]*cacheEntry
+// ZoneCache caches the name servers of zones for recursions. It must
+// be safe for concurrent use.
+type ZoneCache interface {
+	// Get returns the cached name servers of a zone, nil if none.
+	Get(zone *Domain) *ZoneServers
 
-	puts chan *cachePut
-	gets chan *cacheGet
+	// Put puts the name servers of a zone into the cache.
+	// Returns false if the put is rejected.
+	Put(zs *ZoneServers) bool
 }
 
-type cachePut struct {
-	zs    *ZoneServers
-	reply chan bool
+// DefaultCacheSize is the default max number of zones in a cache.
+const DefaultCacheSize = 10000
+
+// CacheStats are the counters of a cache.
+type CacheStats struct {
+	Size      int    // zones cached
+	Hits      uint64 // gets that found the zone
+	Misses    uint64 // gets that did not find the zone, or found it expired
+	Evictions uint64 // zones evicted for the size limit
 }
 
-type cacheGet struct {
-	zone  *Domain
-	reply chan *ZoneServers
+// Cache is a name server cache with a size limit, where the least
+// recently used zones are evicted first. Expired zones are removed when
+// they are got, or by Clean. A Term calls Clean every few minutes, and
+// other long-running users should call it periodically.
+type Cache struct {
+	RegistrarOnly bool
+
+	lock    sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List // of *cacheEntry, most recently used first
+	stats   CacheStats
 }
 
-// NewCache creates a new name server cache
+var _ ZoneCache = new(Cache)
+
+// NewCache creates a new name server cache with the default size.
 func NewCache() *Cache {
+	return NewCacheSize(DefaultCacheSize)
+}
+
+// NewCacheSize creates a new name server cache that holds at most
+// size zones, 0 for no limit.
+func NewCacheSize(size int) *Cache {
 	ret := new(Cache)
-	ret.entries = make(map[string]*cacheEntry)
 	ret.RegistrarOnly = true
-
-	ret.puts = make(chan *cachePut)
-	ret.gets = make(chan *cacheGet)
-
-	go ret.serve()
+	ret.size = size
+	ret.entries = make(map[string]*list.Element)
+	ret.lru = list.New()
 
 	return ret
 }
 
-func (c *Cache) serve() {
-	ticker := time.Tick(time.Minute * 5)
-
-	for {
-		select {
-		case put := <-c.puts:
-			put.reply <- c.put(put.zs)
-		case get := <-c.gets:
-			get.reply <- c.get(get.zone)
-		case <-ticker:
-			c.clean()
-		}
-	}
+func (c *Cache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.zone.key())
 }
 
-func (c *Cache) put(z *ZoneServers) bool {
-	zone := z.Zone()
+// Put puts the zone servers into the cache.
+// Returns true if the put is successful.
+// Returns false if the put is rejected.
+func (c *Cache) Put(zs *ZoneServers) bool {
+	zone := zs.Zone()
 	if zone.IsRoot() {
 		return false // we never cache root
 	}
@@ -61,65 +77,68 @@ func (c *Cache) put(z *ZoneServers) bool {
 		return false
 	}
 
-	key := zone.String()
-	entry := c.entries[key]
-	if entry == nil {
-		c.entries[key] = newCacheEntry(z)
-	} else {
-		entry.Add(z)
-	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
-	return true
-}
-
-func (c *Cache) cleanZone(z *Domain) {
-	zstr := z.String()
-	entry := c.entries[z.String()]
-	if entry == nil {
-		return
+	key := zone.key()
+	if elem := c.entries[key]; elem != nil {
+		elem.Value.(*cacheEntry).Add(zs)
+		c.lru.MoveToFront(elem)
+		return true
 	}
 
-	if entry.Expired() {
-		delete(c.entries, zstr)
+	c.entries[key] = c.lru.PushFront(newCacheEntry(zs))
+	for c.size > 0 && c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
 	}
+
+	return true
 }
 
-func (c *Cache) clean() {
-	toClean := make([]string, 0, 100)
-	for k, v := range c.entries {
-		if v.Expired() {
-			toClean = append(toClean, k)
-		}
-	}
+// Get queries the zone servers for a domain
+func (c *Cache) Get(z *Domain) *ZoneServers {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
-	for _, k := range toClean {
-		delete(c.entries, k)
+	elem := c.entries[z.key()]
+	if elem == nil {
+		c.stats.Misses++
+		return nil
 	}
-}
-
-func (c *Cache) get(z *Domain) *ZoneServers {
-	c.cleanZone(z)
 
-	entry := c.entries[z.String()]
-	if entry == nil {
+	entry := elem.Value.(*cacheEntry)
+	if entry.Expired() {
+		c.remove(elem)
+		c.stats.Misses++
 		return nil
 	}
 
+	c.lru.MoveToFront(elem)
+	c.stats.Hits++
 	return entry.ZoneServers()
 }
 
-// Get queries the zone servers for a domain
-func (c *Cache) Get(z *Domain) *ZoneServers {
-	ch := make(chan *ZoneServers)
-	c.gets <- &cacheGet{z, ch}
-	return <-ch
+// Clean removes the expired zones. Nothing is removed in background.
+func (c *Cache) Clean() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*cacheEntry).Expired() {
+			c.remove(elem)
+		}
+		elem = next
+	}
 }
 
-// Put puts the zone servers into the cache.
-// Returns true if the put is successful.
-// Returns false if the put is rejected.
-func (c *Cache) Put(zs *ZoneServers) bool {
-	ch := make(chan bool)
-	c.puts <- &cachePut{zs, ch}
-	return <-ch
+// Stats returns a snapshot of the cache counters.
+func (c *Cache) Stats() CacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ret := c.stats
+	ret.Size = c.lru.Len()
+	return ret
 }
@@ -10,7 +10,7 @@ const (
 
 type cacheEntry struct {
 	zone       *Domain
-	ips        map[uint32]*NameServer
+	ips        map[ipKey]*NameServer
 	resolved   map[string]*Domain
 	unresolved map[string]*Domain
 	expires    time.Time
@@ -21,7 +21,7 @@ func (e *cacheEntry) Expired() bool {
 }
 
 func (e *cacheEntry) addResolved(d *Domain) {
-	s := d.String()
+	s := d.key()
 	e.resolved[s] = d
 	if e.unresolved[s] != nil {
 		delete(e.unresolved, s)
@@ -31,7 +31,7 @@ func (e *cacheEntry) addResolved(d *Domain) {
 func emptyCacheEntry(zone *Domain) *cacheEntry {
 	return &cacheEntry{
 		zone,
-		make(map[uint32]*NameServer),
+		make(map[ipKey]*NameServer),
 		make(map[string]*Domain),
 		make(map[string]*Domain),
 		time.Now().Add(cacheLifeSpan),
@@ -49,13 +49,16 @@ func (e *cacheEntry) Add(zs *ZoneServers) {
 		panic("zone mismatch")
 	}
 
+	zs.lock.Lock()
+	defer zs.lock.Unlock()
+
 	for key, ns := range zs.ips {
 		e.ips[key] = ns
 		e.addResolved(ns.Domain)
 	}
 
 	for key, d := range zs.unresolved {
-		s := d.String()
+		s := d.key()
 		if key != s {
 			panic("bug")
 		}
@@ -0,0 +1,190 @@
+package dns8
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// CacheSnapshotVersion is the version of the cache snapshot format.
+// It is bumped on any incompatible change.
+const CacheSnapshotVersion = 1
+
+// cacheSnapshot is the file format of a cache snapshot, a json object.
+// Zones are listed with the most recently used first.
+type cacheSnapshot struct {
+	Version int             `json:"version"`
+	Zones   []*zoneSnapshot `json:"zones"`
+}
+
+type zoneSnapshot struct {
+	Zone       string            `json:"zone"`
+	Expires    time.Time         `json:"expires"`
+	Servers    []*serverSnapshot `json:"servers"`
+	Unresolved []string          `json:"unresolved,omitempty"`
+}
+
+type serverSnapshot struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+}
+
+func (e *cacheEntry) snapshot() *zoneSnapshot {
+	ret := &zoneSnapshot{
+		Zone:    e.zone.String(),
+		Expires: e.expires.UTC(),
+	}
+
+	for _, ns := range e.ips {
+		ret.Servers = append(ret.Servers, &serverSnapshot{
+			Name: ns.Domain.String(),
+			IP:   ns.IP.String(),
+		})
+	}
+	sort.Slice(ret.Servers, func(i, j int) bool {
+		a, b := ret.Servers[i], ret.Servers[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.IP < b.IP
+	})
+
+	for s := range e.unresolved {
+		ret.Unresolved = append(ret.Unresolved, s)
+	}
+	sort.Strings(ret.Unresolved)
+
+	return ret
+}
+
+func (z *zoneSnapshot) entry() (*cacheEntry, error) {
+	zone, e := ParseDomain(z.Zone)
+	if e != nil {
+		return nil, e
+	}
+
+	ret := emptyCacheEntry(zone)
+	ret.expires = z.Expires
+
+	for _, s := range z.Servers {
+		d, e := ParseDomain(s.Name)
+		if e != nil {
+			return nil, e
+		}
+		ip := net.ParseIP(s.IP)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip %q of %v", s.IP, d)
+		}
+
+		ret.ips[keyOfIP(ip)] = &NameServer{Zone: zone, Domain: d, IP: ip}
+		ret.addResolved(d)
+	}
+
+	for _, s := range z.Unresolved {
+		d, e := ParseDomain(s)
+		if e != nil {
+			return nil, e
+		}
+		if ret.resolved[d.key()] == nil {
+			ret.unresolved[d.key()] = d
+		}
+	}
+
+	return ret, nil
+}
+
+// Snapshot writes the cached zones with their expiry times to w.
+func (c *Cache) Snapshot(w io.Writer) error {
+	snap := &cacheSnapshot{Version: CacheSnapshotVersion}
+
+	c.lock.Lock()
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*cacheEntry)
+		if !entry.Expired() {
+			snap.Zones = append(snap.Zones, entry.snapshot())
+		}
+	}
+	c.lock.Unlock()
+
+	return json.NewEncoder(w).Encode(snap)
+}
+
+// Restore reads a snapshot from r into the cache, and returns the
+// number of zones restored. Expired zones, and zones that are already
+// cached, are skipped.
+func (c *Cache) Restore(r io.Reader) (int, error) {
+	snap := new(cacheSnapshot)
+	if e := json.NewDecoder(r).Decode(snap); e != nil {
+		return 0, e
+	}
+	if snap.Version != CacheSnapshotVersion {
+		return 0, fmt.Errorf("cache snapshot version %d, expect %d",
+			snap.Version, CacheSnapshotVersion)
+	}
+
+	entries := make([]*cacheEntry, 0, len(snap.Zones))
+	for _, z := range snap.Zones {
+		entry, e := z.entry()
+		if e != nil {
+			return 0, e
+		}
+		entries = append(entries, entry)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	n := 0
+	for _, entry := range entries {
+		if c.size > 0 && c.lru.Len() >= c.size {
+			break
+		}
+
+		key := entry.zone.key()
+		if entry.Expired() || c.entries[key] != nil {
+			continue
+		}
+		c.entries[key] = c.lru.PushBack(entry)
+		n++
+	}
+
+	return n, nil
+}
+
+// SaveFile saves a snapshot of the cache to a file. The file is
+// replaced at once, so a reader never sees a partial snapshot.
+func (c *Cache) SaveFile(path string) error {
+	f, e := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".")
+	if e != nil {
+		return e
+	}
+	defer os.Remove(f.Name()) // no-op after the rename
+
+	if e := c.Snapshot(f); e != nil {
+		f.Close()
+		return e
+	}
+	if e := f.Close(); e != nil {
+		return e
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// LoadFile restores the cache from a snapshot file, and returns the
+// number of zones restored.
+func (c *Cache) LoadFile(path string) (int, error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return 0, e
+	}
+	defer f.Close()
+
+	return c.Restore(f)
+}
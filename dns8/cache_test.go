@@ -0,0 +1,95 @@
+package dns8
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheLRU(t *testing.T) {
+	c := NewCacheSize(2)
+
+	put := func(zone string) {
+		zs := NewZoneServers(D(zone))
+		zs.Add(D("ns."+zone), net.ParseIP("10.0.0.1"))
+		if !c.Put(zs) {
+			t.Fatalf("put %s rejected", zone)
+		}
+	}
+
+	put("com")
+	put("net")
+	if c.Get(D("com")) == nil { // com is used after net
+		t.Fatal("com should be cached")
+	}
+	put("org")
+
+	if c.Get(D("net")) != nil {
+		t.Error("net should be evicted")
+	}
+	if c.Get(D("org")) == nil {
+		t.Error("org should be cached")
+	}
+
+	stats := c.Stats()
+	want := CacheStats{Size: 2, Hits: 2, Misses: 1, Evictions: 1}
+	if stats != want {
+		t.Errorf("expect %+v, got %+v", want, stats)
+	}
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	c := NewCache()
+	zs := NewZoneServers(D("com"))
+	zs.Add(D("a.gtld.com"), net.ParseIP("10.0.0.1"), net.ParseIP("fd00::1"))
+	zs.Add(D("b.gtld.com"))
+	c.Put(zs)
+
+	buf := new(bytes.Buffer)
+	if e := c.Snapshot(buf); e != nil {
+		t.Fatal(e)
+	}
+
+	restored := NewCache()
+	n, e := restored.Restore(buf)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if n != 1 {
+		t.Fatalf("expect one zone restored, got %d", n)
+	}
+
+	got := restored.Get(D("com"))
+	if got == nil {
+		t.Fatal("com should be restored")
+	}
+	if len(got.ListResolved()) != 2 || len(got.ListUnresolved()) != 1 {
+		t.Errorf("servers not restored: %v", got.List())
+	}
+
+	_, e = NewCache().Restore(strings.NewReader(`{"version":99}`))
+	if e == nil {
+		t.Error("expect an error for an unknown version")
+	}
+}
+
+func TestTermClean(t *testing.T) {
+	c := fakeInternet().NewClient()
+	defer c.Close()
+
+	tm := NewTerm(c)
+	cache := tm.Cache.(*Cache)
+	zs := NewZoneServers(D("com"))
+	zs.Add(D("a.gtld.com"), net.ParseIP("10.0.0.1"))
+	cache.Put(zs)
+	cache.entries["com"].Value.(*cacheEntry).expires = time.Now().Add(-time.Second)
+
+	if _, e := tm.Q(Q(D("example.com"), A, net.ParseIP("10.0.1.1"))); e != nil {
+		t.Fatal(e)
+	}
+	if n := cache.Stats().Size; n != 0 {
+		t.Errorf("expect the expired zone cleaned, got %d zones", n)
+	}
+}
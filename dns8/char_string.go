@@ -0,0 +1,48 @@
+package dns8
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// unpackCharString unpacks a character string, a length byte followed
+// by the bytes, and returns the bytes left.
+func unpackCharString(buf []byte) ([]byte, []byte, error) {
+	if len(buf) < 1 {
+		return nil, nil, errors.New("missing character string")
+	}
+	n := int(buf[0])
+	if len(buf) < 1+n {
+		return nil, nil, errors.New("character string overflows")
+	}
+	return buf[1 : 1+n], buf[1+n:], nil
+}
+
+// packCharString packs a character string. Bytes over 255 are cut.
+func packCharString(buf *bytes.Buffer, s []byte) {
+	if len(s) > 255 {
+		s = s[:255]
+	}
+	buf.WriteByte(byte(len(s)))
+	buf.Write(s)
+}
+
+// quoteCharString prints a character string in quotes in the
+// presentation format, escaping the quotes, the backslashes and the
+// bytes that are not printable.
+func quoteCharString(out *bytes.Buffer, s []byte) {
+	out.WriteByte('"')
+	for _, b := range s {
+		switch {
+		case b == '"' || b == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(b)
+		case b < 0x20 || b > 0x7e:
+			fmt.Fprintf(out, "\\%03d", b)
+		default:
+			out.WriteByte(b)
+		}
+	}
+	out.WriteByte('"')
+}
@@ -1,48 +1,85 @@
 package dns8
 
 import (
+	"container/heap"
+	"context"
 	"encoding/hex"
 	"log"
 	"net"
+	"sync/atomic"
 	"time"
 )
 
 // Client is a DNS query client
 type Client struct {
-	conn   *net.UDPConn
-	idPool *idPool
+	transport Transport
+	idPool    *idPool
 
 	jobs       map[uint16]*job
 	newJobs    chan *job
 	sendErrors chan *job
+	cancels    chan *job
 	recvs      chan *Message
-	timer      <-chan time.Time
+	deadlines  jobHeap
+	timer      *time.Timer
+	rtts       *rttTable
+	limiter    *rateLimiter
+	cases      *caseTable
+	flights    *flights
+	counters   *clientCounters // allocated for 64-bit atomic alignment
 
 	closed  bool
 	closing chan struct{}
 
 	Logger *log.Logger
+	Health *Health // tracks server failures, can be shared by clients
+
+	// Bounds of the per-server query timeout, which is derived from
+	// the measured round trip time of the server.
+	MinTimeout time.Duration
+	MaxTimeout time.Duration
+
+	// Query rate limits in queries per second, 0 for no limit.
+	// Queries over the limit are queued.
+	RateLimit       float64 // for all queries
+	ServerRateLimit float64 // for each server ip
+
+	// Use0x20 randomizes the letter cases of the query names, and
+	// requires the replies to echo them exactly. A server that only
+	// replies in other cases is retried with plain names, and when
+	// that succeeds, it is recorded and queried without 0x20 after.
+	Use0x20 bool
 }
 
-// NewClientPort creates a client at a particular port
+// NewClientPort creates a client at a particular port.
+// The client listens on both IPv4 and IPv6 when IPv6 is available.
 func NewClientPort(port uint16) (*Client, error) {
-	ret := new(Client)
-
-	addr := &net.UDPAddr{Port: int(port)}
-	if port == 0 {
-		addr = nil
-	}
-
-	var e error
-	ret.conn, e = net.ListenUDP("udp4", addr)
+	t, e := NewNetTransport(port)
 	if e != nil {
 		return nil, e
 	}
 
+	return NewClientTransport(t), nil
+}
+
+// NewClientTransport creates a client that works over a transport.
+func NewClientTransport(t Transport) *Client {
+	ret := new(Client)
+	ret.transport = t
+
 	ret.newJobs = make(chan *job, 0)
 	ret.sendErrors = make(chan *job, 10)
+	ret.cancels = make(chan *job, 10)
 	ret.recvs = make(chan *Message, 10)
-	ret.timer = time.Tick(time.Millisecond * 100)
+	ret.timer = time.NewTimer(time.Hour)
+	ret.rtts = newRTTTable()
+	ret.Health = NewHealth()
+	ret.limiter = newRateLimiter()
+	ret.cases = newCaseTable()
+	ret.flights = newFlights()
+	ret.counters = new(clientCounters)
+	ret.MinTimeout = DefaultMinTimeout
+	ret.MaxTimeout = DefaultMaxTimeout
 	ret.idPool = newIDPool()
 	ret.jobs = make(map[uint16]*job)
 	ret.closing = make(chan struct{})
@@ -50,7 +87,7 @@ func NewClientPort(port uint16) (*Client, error) {
 	go ret.recv()
 	go ret.serve()
 
-	return ret, nil
+	return ret
 }
 
 // NewClient creates a client at port 0 (any port available).
@@ -58,26 +95,18 @@ func NewClient() (*Client, error) {
 	return NewClientPort(0)
 }
 
-const packetMaxSize = 1600
-
-func newRecvBuf() []byte {
-	return make([]byte, packetMaxSize)
-}
-
 // Close the client (asyncly)
 func (c *Client) Close() error {
 	c.closed = true
 	c.closing <- struct{}{}
-	return c.conn.Close()
+	return c.transport.Close()
 }
 
 func (c *Client) recv() {
-	buf := newRecvBuf()
-
 	for {
-		n, addr, e := c.conn.ReadFromUDP(buf)
+		bs, addr, e := c.transport.Recv()
 		if e != nil {
-			if c.closed {
+			if c.closed || e == ErrClosed {
 				break
 			}
 
@@ -87,11 +116,11 @@ func (c *Client) recv() {
 			continue
 		}
 
-		p, e := Unpack(buf[:n])
+		p, e := Unpack(bs)
 		if e != nil {
 			if c.Logger != nil {
 				c.Logger.Print("unpack: ", e)
-				c.Logger.Print(hex.Dump(buf[:n]))
+				c.Logger.Print(hex.Dump(bs))
 			}
 
 			continue
@@ -103,8 +132,6 @@ func (c *Client) recv() {
 			Timestamp:  time.Now(),
 		}
 		c.recvs <- m
-
-		buf = newRecvBuf()
 	}
 }
 
@@ -112,6 +139,7 @@ func (c *Client) delJob(id uint16) {
 	if c.jobs[id] != nil {
 		delete(c.jobs, id)
 		c.idPool.Return(id)
+		atomic.AddInt64(&c.counters.inFlight, -1)
 	}
 }
 
@@ -124,6 +152,12 @@ func (c *Client) serve() {
 			id := job.id
 			bugOn(c.jobs[id] != nil)
 			c.jobs[id] = job
+			atomic.AddInt64(&c.counters.inFlight, 1)
+
+			heap.Push(&c.deadlines, job)
+			if c.deadlines.top() == job {
+				c.resetTimer()
+			}
 		case job := <-c.sendErrors:
 			/*
 				Need to check if it is still the same job. In some rare racing
@@ -135,6 +169,15 @@ func (c *Client) serve() {
 				// still the same job
 				c.delJob(job.id)
 			}
+		case job := <-c.cancels:
+			// the job might be finished already, like sendErrors
+			if c.jobs[job.id] == job {
+				c.delJob(job.id) // frees the id before returning
+				job.CloseErr(ErrCanceled)
+			} else if job.stopTCP != nil {
+				// the tcp retry ends the job with ErrCanceled
+				job.stopTCP()
+			}
 		case m := <-c.recvs:
 			id := m.Packet.ID
 			job := c.jobs[id]
@@ -143,36 +186,159 @@ func (c *Client) serve() {
 				if c.Logger != nil {
 					c.Logger.Printf("recved zombie msg with id %d", id)
 				}
+			} else if e := job.match(m); e != nil {
+				// keep waiting for the genuine reply
+				atomic.AddUint64(&c.counters.mismatches, 1)
+				if c.Logger != nil {
+					c.Logger.Printf("suspected spoofing: %v, %v",
+						e, m.Packet)
+				}
+			} else if !c.checkCase(job, m) {
+				// might be spoofed, keep waiting for the genuine reply
+				job.caseMismatch = true
 			} else {
 				bugOn(job.id != id)
-				job.CloseRecv(m)
+				ip := m.RemoteAddr.IP
+				c.rtts.sample(ip, m.Timestamp.Sub(job.exchange.Send.Timestamp))
+				c.Health.Succeed(ip)
+				if m.Packet.Flag&FlagTC != 0 {
+					ctx, stop := context.WithTimeout(
+						context.Background(), c.MaxTimeout)
+					job.stopTCP = stop
+					go c.retryTCP(ctx, job)
+				} else {
+					if !job.exchange.Mixed {
+						c.cases.verify(ip, true)
+					}
+					job.CloseRecv(m)
+				}
 				c.delJob(id)
 			}
-		case now := <-c.timer:
-			timeouts := make([]uint16, 0, 1024)
-
-			for id, job := range c.jobs {
-				bugOn(job.id != id)
-				if job.deadline.Before(now) {
-					job.CloseErr(errTimeout)
+		case now := <-c.timer.C:
+			for {
+				job := c.deadlines.top()
+				if job == nil || job.deadline.After(now) {
+					break
+				}
+				heap.Pop(&c.deadlines)
 
-					// iterating the map, so delete afterwards for safty
-					timeouts = append(timeouts, id)
+				// the job might be finished already
+				if c.jobs[job.id] == job {
+					c.expire(job)
 				}
 			}
 
-			for _, id := range timeouts {
-				c.delJob(id)
-			}
+			c.resetTimer()
 		}
 	}
 }
 
-const timeout = time.Second * 3
+// expire ends a job that is not answered before its deadline. When
+// the only replies are in other letter cases, the server is suspected
+// not preserving the cases, and the job ends with errCaseMismatch.
+func (c *Client) expire(job *job) {
+	ip := job.exchange.Send.RemoteAddr.IP
+	if job.caseMismatch {
+		c.cases.suspect(ip)
+		job.CloseErr(errCaseMismatch)
+	} else {
+		if !job.exchange.Mixed {
+			c.cases.verify(ip, false)
+		}
+		job.CloseErr(errTimeout)
+		atomic.AddUint64(&c.counters.timeouts, 1)
+		c.rtts.timeout(ip)
+		c.Health.Fail(ip)
+	}
+	c.delJob(job.id)
+}
+
+// resetTimer sets the timer to fire at the earliest deadline.
+func (c *Client) resetTimer() {
+	c.timer.Stop()
+	select {
+	case <-c.timer.C:
+	default:
+	}
+
+	job := c.deadlines.top()
+	if job == nil {
+		c.timer.Reset(time.Hour)
+		return
+	}
+	c.timer.Reset(job.deadline.Sub(time.Now()))
+}
+
+// RTT returns the measured round trip time statistics of a server.
+// It returns false if the client has never queried the server.
+func (c *Client) RTT(ip net.IP) (RTTStat, bool) {
+	return c.rtts.get(ip)
+}
+
+// RTTs returns the measured round trip time statistics of all the
+// servers queried, keyed by the ip address string.
+func (c *Client) RTTs() map[string]RTTStat {
+	return c.rtts.all()
+}
+
+// Timeout returns the timeout that the client will use for
+// the next query to a server.
+func (c *Client) Timeout(ip net.IP) time.Duration {
+	return c.rtts.deadline(ip, c.MinTimeout, c.MaxTimeout)
+}
 
 // Send schedules a new query. It sends the exchange data back
-// to the channel
+// to the channel. When the query is over the rate limit, Send
+// blocks until the query can be sent. When an identical query is
+// outstanding, no packet is sent, and the exchange is a copy of the
+// reply for that query.
 func (c *Client) Send(q *QueryPrinter, ch chan<- *Exchange) {
+	c.start(context.Background(), q, ch)
+}
+
+// start schedules a new query, and returns the waiter of it. When ctx
+// is canceled while waiting for the rate limit, it sends back an
+// exchange with ErrCanceled, and the tokens reserved are refunded
+// unless other waiters have joined the flight.
+func (c *Client) start(ctx context.Context, q *QueryPrinter,
+	ch chan<- *Exchange,
+) *waiter {
+	w, first := c.flights.join(q, ch)
+	if !first {
+		atomic.AddUint64(&c.counters.coalesced, 1)
+		return w
+	}
+
+	r := c.limiter.reserve(q.Server.IP, c.RateLimit, c.ServerRateLimit)
+	queued := r.wait
+	if queued > 0 {
+		timer := time.NewTimer(queued)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			c.cancel(w)
+			if c.flights.abandoned(w.flight) {
+				timer.Stop()
+				c.limiter.refund(r)
+				return w
+			}
+
+			// other waiters have joined the flight
+			go func() {
+				<-timer.C
+				c.launch(w.flight, q, queued)
+			}()
+			return w
+		}
+	}
+
+	c.launch(w.flight, q, queued)
+	return w
+}
+
+// launch sends the query of a flight, unless all its waiters have
+// left.
+func (c *Client) launch(f *flight, q *QueryPrinter, queued time.Duration) {
 	id := c.idPool.Fetch()
 	message := newMessage(q.Query, id)
 	if message.RemoteAddr.Port == 0 {
@@ -183,21 +349,29 @@ func (c *Client) Send(q *QueryPrinter, ch chan<- *Exchange) {
 		Query:     q.Query,
 		Send:      message,
 		PrintFlag: q.PrintFlag,
+		Queued:    queued,
+	}
+	if (c.Use0x20 || q.Use0x20) && c.cases.preserves(message.RemoteAddr.IP) {
+		mixCase(message.Packet.Bytes)
+		exchange.Mixed = true
 	}
 	job := &job{
 		id:       id,
 		exchange: exchange,
-		deadline: time.Now().Add(timeout),
-		printer:  q.Printer,
-		c:        ch,
+		deadline: time.Now().Add(c.Timeout(message.RemoteAddr.IP)),
+		flight:   f,
+		flights:  c.flights,
 	}
 
 	c.newJobs <- job // set a place in mapping
 
-	if q.Printer != nil {
-		exchange.printSend(q.Printer)
+	// the job is mapped before it can be canceled
+	if !c.flights.send(f, job) {
+		c.cancels <- job
+		return
 	}
 
+	atomic.AddUint64(&c.counters.sent, 1)
 	e := c.send(message)
 	if e != nil {
 		job.CloseErr(e)
@@ -207,9 +381,95 @@ func (c *Client) Send(q *QueryPrinter, ch chan<- *Exchange) {
 	}
 }
 
+// cancel ends the waiter with ErrCanceled. When no one else waits for
+// the flight, the outstanding query is aborted.
+func (c *Client) cancel(w *waiter) {
+	taken, job := c.flights.leave(w)
+	if job != nil {
+		c.cancels <- job
+	}
+	if taken {
+		w.end(&Exchange{
+			Query:     w.Query,
+			Error:     ErrCanceled,
+			PrintFlag: w.PrintFlag,
+		})
+	}
+}
+
+// retryTCP retries a truncated exchange over TCP, and closes the job
+// with the full reply. The retry is aborted with ErrCanceled when ctx
+// is canceled, which happens when all the waiters of the job leave.
+func (c *Client) retryTCP(ctx context.Context, job *job) {
+	defer job.stopTCP()
+
+	x := job.exchange
+	x.TCP = true
+
+	send := x.Send
+	bs, e := c.transport.ExchangeTCP(ctx, send.Packet.Bytes, send.RemoteAddr)
+	if e != nil {
+		if e == context.Canceled {
+			e = ErrCanceled
+		} else if ne, ok := e.(net.Error); ok && ne.Timeout() {
+			e = errTimeout
+		}
+		job.CloseErr(e)
+		return
+	}
+
+	p, e := Unpack(bs)
+	if e != nil {
+		job.CloseErr(e)
+		return
+	}
+
+	m := &Message{
+		RemoteAddr: send.RemoteAddr,
+		Packet:     p,
+		Timestamp:  time.Now(),
+	}
+	if e := job.match(m); e != nil {
+		atomic.AddUint64(&c.counters.mismatches, 1)
+		job.CloseErr(e)
+		return
+	}
+	if !c.checkCase(job, m) {
+		// a tcp reply is hardly spoofed, but is verified all the same
+		c.cases.suspect(m.RemoteAddr.IP)
+		job.CloseErr(errCaseMismatch)
+		return
+	}
+
+	if !x.Mixed {
+		c.cases.verify(m.RemoteAddr.IP, true)
+	}
+	job.CloseRecv(m)
+}
+
+// checkCase checks if the reply echoes the randomized letter cases
+// of the query name. A mismatch is counted and logged.
+func (c *Client) checkCase(job *job, m *Message) bool {
+	x := job.exchange
+	if !x.Mixed || sameCase(x.Send.Packet.Bytes, m.Packet.Bytes) {
+		return true
+	}
+
+	atomic.AddUint64(&c.counters.caseMismatches, 1)
+	if c.Logger != nil {
+		c.Logger.Printf("case mismatch from %v: %v", m.RemoteAddr, m.Packet)
+	}
+	return false
+}
+
+// CaseQuirks returns the servers that are found not preserving the
+// letter cases of query names.
+func (c *Client) CaseQuirks() []net.IP {
+	return c.cases.list()
+}
+
 func (c *Client) send(m *Message) error {
-	_, e := c.conn.WriteToUDP(m.Packet.Bytes, m.RemoteAddr)
-	return e
+	return c.transport.Send(m.Packet.Bytes, m.RemoteAddr)
 }
 
 // AsyncQuery sends a query and call-back f with the exchange.
@@ -229,3 +489,19 @@ func (c *Client) Query(q *QueryPrinter) *Exchange {
 
 	return <-ch
 }
+
+// QueryContext queries the query and returns the exchange. When ctx is
+// canceled before the reply, the exchange is aborted with ErrCanceled,
+// and its id is freed for reuse.
+func (c *Client) QueryContext(ctx context.Context, q *QueryPrinter) *Exchange {
+	ch := make(chan *Exchange, 1)
+	w := c.start(ctx, q, ch)
+
+	select {
+	case ret := <-ch:
+		return ret
+	case <-ctx.Done():
+		c.cancel(w)
+		return <-ch
+	}
+}
@@ -0,0 +1,55 @@
+package dns8
+
+import (
+	"sync/atomic"
+)
+
+// ClientStats are the counters of a client.
+type ClientStats struct {
+	Sent     uint64 // queries sent
+	Timeouts uint64 // queries timed out
+	InFlight int    // queries waiting for replies
+
+	// Mismatches counts the replies that match the id of a pending
+	// query, but not its server address or question. These are
+	// suspected to be spoofed and are dropped.
+	Mismatches uint64
+
+	// CaseMismatches counts the replies that do not echo the
+	// randomized letter cases of the query name when Use0x20 is on.
+	// It is either spoofing or a server not preserving the cases.
+	CaseMismatches uint64
+
+	// Coalesced counts the queries that share the reply of an
+	// identical outstanding query, and hence are not sent.
+	Coalesced uint64
+
+	// IDPressure is the portion of the query ids in the pool that
+	// are in flight. Sending blocks when it reaches 1.
+	IDPressure float64
+}
+
+type clientCounters struct {
+	sent       uint64
+	timeouts   uint64
+	mismatches uint64
+	inFlight   int64
+
+	caseMismatches uint64
+	coalesced      uint64
+}
+
+// Stats returns a snapshot of the client counters.
+func (c *Client) Stats() ClientStats {
+	n := atomic.LoadInt64(&c.counters.inFlight)
+	return ClientStats{
+		Sent:       atomic.LoadUint64(&c.counters.sent),
+		Timeouts:   atomic.LoadUint64(&c.counters.timeouts),
+		InFlight:   int(n),
+		IDPressure: float64(n) / float64(nprepare),
+		Mismatches: atomic.LoadUint64(&c.counters.mismatches),
+
+		CaseMismatches: atomic.LoadUint64(&c.counters.caseMismatches),
+		Coalesced:      atomic.LoadUint64(&c.counters.coalesced),
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 )
 
-// rdata type
+// rdata type, see www.iana.org/assignments/dns-parameters
 const (
 	A     = 1
 	NS    = 2
@@ -22,52 +22,243 @@ const (
 	MINFO = 14
 	MX    = 15
 	TXT   = 16
-	AAAA  = 28
+
+	RP      = 17
+	AFSDB   = 18
+	X25     = 19
+	ISDN    = 20
+	RT      = 21
+	NSAP    = 22
+	NSAPPTR = 23
+	SIG     = 24
+	KEY     = 25
+	PX      = 26
+	GPOS    = 27
+
+	AAAA   = 28
+	LOC    = 29
+	NXT    = 30
+	EID    = 31
+	NIMLOC = 32
+	SRV    = 33
+	ATMA   = 34
+	NAPTR  = 35
+	KX     = 36
+	CERT   = 37
+	A6     = 38
+	DNAME  = 39
+	SINK   = 40
+	OPT    = 41
+	APL    = 42
+
+	DS         = 43
+	SSHFP      = 44
+	IPSECKEY   = 45
+	RRSIG      = 46
+	NSEC       = 47
+	DNSKEY     = 48
+	DHCID      = 49
+	NSEC3      = 50
+	NSEC3PARAM = 51
+	TLSA       = 52
+	SMIMEA     = 53
+	HIP        = 55
+	NINFO      = 56
+	RKEY       = 57
+	TALINK     = 58
+	CDS        = 59
+	CDNSKEY    = 60
+	OPENPGPKEY = 61
+	CSYNC      = 62
+	ZONEMD     = 63
+
+	SVCB  = 64
+	HTTPS = 65
+	DSYNC = 66
+	HHIT  = 67
+	BRID  = 68
+
+	SPF    = 99
+	UINFO  = 100
+	UID    = 101
+	GID    = 102
+	UNSPEC = 103
+	NID    = 104
+	L32    = 105
+	L64    = 106
+	LP     = 107
+	EUI48  = 108
+	EUI64  = 109
+	NXNAME = 128
+
+	TKEY  = 249
+	TSIG  = 250
+	IXFR  = 251
+	AXFR  = 252
+	MAILB = 253
+	MAILA = 254
+	ANY   = 255 // also the class for any
+
+	URI      = 256
+	CAA      = 257
+	AVC      = 258
+	DOA      = 259
+	AMTRELAY = 260
+	RESINFO  = 261
+	WALLET   = 262
+	CLA      = 263
+	IPN      = 264
+
+	TA  = 32768
+	DLV = 32769
 )
 
 // class code
 const (
-	IN = 1
-	CS = 2
-	CH = 3
-	HS = 4
+	IN   = 1
+	CS   = 2
+	CH   = 3
+	HS   = 4
+	NONE = 254
 )
 
 var (
 	typeStrings = map[uint16]string{
 		A:     "a",
-		AAAA:  "aaaa",
 		NS:    "ns",
-		MX:    "mx",
+		MD:    "md",
+		MF:    "mf",
 		CNAME: "cname",
-		TXT:   "txt",
 		SOA:   "soa",
+		MB:    "mb",
+		MG:    "mg",
+		MR:    "mr",
 		NULL:  "null",
+		WKS:   "wks",
 		PTR:   "ptr",
+		HINFO: "hinfo",
+		MINFO: "minfo",
+		MX:    "mx",
+		TXT:   "txt",
+
+		RP:      "rp",
+		AFSDB:   "afsdb",
+		X25:     "x25",
+		ISDN:    "isdn",
+		RT:      "rt",
+		NSAP:    "nsap",
+		NSAPPTR: "nsap-ptr",
+		SIG:     "sig",
+		KEY:     "key",
+		PX:      "px",
+		GPOS:    "gpos",
+
+		AAAA:   "aaaa",
+		LOC:    "loc",
+		NXT:    "nxt",
+		EID:    "eid",
+		NIMLOC: "nimloc",
+		SRV:    "srv",
+		ATMA:   "atma",
+		NAPTR:  "naptr",
+		KX:     "kx",
+		CERT:   "cert",
+		A6:     "a6",
+		DNAME:  "dname",
+		SINK:   "sink",
+		OPT:    "opt",
+		APL:    "apl",
+
+		DS:         "ds",
+		SSHFP:      "sshfp",
+		IPSECKEY:   "ipseckey",
+		RRSIG:      "rrsig",
+		NSEC:       "nsec",
+		DNSKEY:     "dnskey",
+		DHCID:      "dhcid",
+		NSEC3:      "nsec3",
+		NSEC3PARAM: "nsec3param",
+		TLSA:       "tlsa",
+		SMIMEA:     "smimea",
+		HIP:        "hip",
+		NINFO:      "ninfo",
+		RKEY:       "rkey",
+		TALINK:     "talink",
+		CDS:        "cds",
+		CDNSKEY:    "cdnskey",
+		OPENPGPKEY: "openpgpkey",
+		CSYNC:      "csync",
+		ZONEMD:     "zonemd",
+
+		SVCB:  "svcb",
+		HTTPS: "https",
+		DSYNC: "dsync",
+		HHIT:  "hhit",
+		BRID:  "brid",
+
+		SPF:    "spf",
+		UINFO:  "uinfo",
+		UID:    "uid",
+		GID:    "gid",
+		UNSPEC: "unspec",
+		NID:    "nid",
+		L32:    "l32",
+		L64:    "l64",
+		LP:     "lp",
+		EUI48:  "eui48",
+		EUI64:  "eui64",
+		NXNAME: "nxname",
+
+		TKEY:  "tkey",
+		TSIG:  "tsig",
+		IXFR:  "ixfr",
+		AXFR:  "axfr",
+		MAILB: "mailb",
+		MAILA: "maila",
+		ANY:   "any",
+
+		URI:      "uri",
+		CAA:      "caa",
+		AVC:      "avc",
+		DOA:      "doa",
+		AMTRELAY: "amtrelay",
+		RESINFO:  "resinfo",
+		WALLET:   "wallet",
+		CLA:      "cla",
+		IPN:      "ipn",
+
+		TA:  "ta",
+		DLV: "dlv",
 	}
 
 	classStrings = map[uint16]string{
-		IN: "in",
-		CS: "cs",
-		CH: "ch",
-		HS: "hs",
+		IN:   "in",
+		CS:   "cs",
+		CH:   "ch",
+		HS:   "hs",
+		NONE: "none",
+		ANY:  "any",
 	}
 )
 
-// TypeString returns the string of a type field
+// TypeString returns the string of a type field. Unknown types are
+// like type65280, as in RFC 3597.
 func TypeString(t uint16) string {
+	registry.RLock()
 	s, found := typeStrings[t]
+	registry.RUnlock()
 	if found {
 		return s
 	}
-	return fmt.Sprintf("t%d", t)
+	return fmt.Sprintf("type%d", t)
 }
 
-// ClassString returns the string of a class field
+// ClassString returns the string of a class field. Unknown classes are
+// like class32, as in RFC 3597.
 func ClassString(c uint16) string {
 	s, found := classStrings[c]
 	if found {
 		return s
 	}
-	return fmt.Sprintf("c%d", c)
+	return fmt.Sprintf("class%d", c)
 }
@@ -1,7 +1,11 @@
 package dns8
 
 import (
-	"errors"
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // cursor is a query cursor in a query terminal.
@@ -11,34 +15,46 @@ type cursor struct {
 	*TermConfig // conveniently inherits the term options
 	*stack
 
-	client *Client
-	nquery int
-	e      error
+	client   *Client
+	ctx      context.Context
+	cancel   context.CancelFunc
+	deadline time.Time // of the time budget, zero for no limit
+	nquery   *int64    // shared with the forks
+	depth    int       // depth of the stack bottom, for the forks
+	e        error
 }
 
 var _ Cursor = new(cursor)
 
 func newCursor(cfg *TermConfig, c *Client) *cursor {
+	return newCursorContext(context.Background(), cfg, c)
+}
+
+// newCursorContext creates a cursor that runs in ctx. When the config
+// has a time budget, the cursor must be closed after use.
+func newCursorContext(ctx context.Context, cfg *TermConfig, c *Client) *cursor {
 	ret := new(cursor)
 
 	ret.TermConfig = cfg
 	ret.stack = newStack()
 	ret.Printer = NewPrinter(cfg.Log)
 	ret.client = c
+	ret.ctx = ctx
+	ret.nquery = new(int64)
+
+	if cfg.Timeout > 0 {
+		ret.deadline = time.Now().Add(cfg.Timeout)
+		ret.ctx, ret.cancel = context.WithDeadline(ctx, ret.deadline)
+	}
 
 	return ret
 }
 
-// Query tree limits
-const (
-	MaxDepth = 30
-	MaxQuery = 500
-)
-
-var (
-	errTooDeep        = errors.New("too deep")
-	errTooManyQueries = errors.New("too many queries")
-)
+func (c *cursor) close() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
 
 // P returns the printer.
 func (c *cursor) P() *Printer { return c.Printer }
@@ -46,33 +62,133 @@ func (c *cursor) P() *Printer { return c.Printer }
 // Error returns the cursor error, if any
 func (c *cursor) E() error { return c.e }
 
+// Config returns the term config that the cursor runs with.
+func (c *cursor) Config() *TermConfig { return c.TermConfig }
+
+// Context returns the context that the cursor runs in.
+func (c *cursor) Context() context.Context { return c.ctx }
+
+// stop stops the cursor with error e.
+func (c *cursor) stop(e error) {
+	c.e = e
+	c.Printf("error %v", c.e)
+}
+
+// stopped checks if the context is done, and stops the cursor with
+// ErrTimeBudget or ErrCanceled if so.
+func (c *cursor) stopped() bool {
+	if c.e != nil {
+		return true
+	}
+	if c.ctx.Err() == nil {
+		return false
+	}
+
+	if !c.deadline.IsZero() && !time.Now().Before(c.deadline) {
+		c.stop(ErrTimeBudget)
+	} else {
+		c.stop(ErrCanceled)
+	}
+	return true
+}
+
 // Q queries a query with the cursor.
 func (c *cursor) Q(q *Query) (*Leaf, error) {
-	if c.e != nil {
+	if c.stopped() {
 		return nil, c.e
 	}
 
-	if c.nquery >= MaxQuery {
-		c.e = errTooManyQueries
-		c.Printf("error %v", c.e)
+	if ret := c.local(q); ret != nil {
+		c.TopAdd(ret)
+		return ret, nil
+	}
+	if ret := c.cached(q); ret != nil {
+		c.TopAdd(ret)
+		return ret, nil
+	}
+
+	if atomic.AddInt64(c.nquery, 1) > int64(c.maxQuery()) {
+		c.stop(ErrTooManyQueries)
 		return nil, c.e
 	}
 
-	c.nquery++
 	ret := c.q(q)
 	c.TopAdd(ret)
-	return ret, c.e
+	if c.stopped() {
+		return ret, c.e
+	}
+
+	if c.RRCache != nil && q.Zone != nil && ret.LastEnd() == EndReply {
+		x := ret.Last()
+		c.RRCache.putReply(q.Zone, x.Send.Packet.Question, x.Recv.Packet)
+	}
+	return ret, nil
+}
+
+// local answers a query to the root servers from the local root zone.
+// It returns nil if there is no mirror, or the mirror is expired.
+func (c *cursor) local(q *Query) *Leaf {
+	z := c.RootZone
+	if z == nil || q.Zone == nil || !q.Zone.IsRoot() || z.Expired() {
+		return nil
+	}
+	if c.dnssecOK(q) {
+		return nil // the mirror keeps no signatures
+	}
+
+	p := z.reply(&Question{q.Domain, q.Type, IN})
+	return c.answered(&Exchange{Query: q, Local: true}, p)
+}
+
+// cached answers a query of a recursion from the record cache. It
+// returns nil if the cache is disabled or cannot answer.
+func (c *cursor) cached(q *Query) *Leaf {
+	if c.RRCache == nil || q.Zone == nil || c.dnssecOK(q) {
+		return nil
+	}
+
+	p := c.RRCache.reply(q.Domain, q.Type)
+	if p == nil {
+		return nil
+	}
+	return c.answered(&Exchange{Query: q, Cached: true}, p)
+}
+
+// dnssecOK checks if the query asks for the DNSSEC records, which
+// are only answered by the servers.
+func (c *cursor) dnssecOK(q *Query) bool {
+	edns := q.Edns
+	if edns == nil {
+		edns = c.Edns
+	}
+	return edns != nil && edns.DO
+}
+
+// answered makes a leaf of an exchange that is answered with p
+// without sending a query.
+func (c *cursor) answered(x *Exchange, p *Packet) *Leaf {
+	x.Recv = &Message{
+		RemoteAddr: x.Query.Server,
+		Packet:     p,
+		Timestamp:  time.Now(),
+	}
+	x.PrintFlag = c.PrintFlag
+	x.printSend(c.Printer)
+	x.printRecv(c.Printer)
+
+	ret := newLeaf(1)
+	ret.add(x, EndReply)
+	return ret
 }
 
 // T queries a task with the cursor
 func (c *cursor) T(t Task) (*Branch, error) {
-	if c.e != nil {
+	if c.stopped() {
 		return nil, c.e
 	}
 
-	if c.Len() >= MaxDepth {
-		c.e = errTooDeep
-		c.Printf("error %v", c.e)
+	if c.depth+c.Len() >= c.maxDepth() {
+		c.stop(ErrTooDeep)
 		return nil, c.e
 	}
 
@@ -90,22 +206,104 @@ func (c *cursor) T(t Task) (*Branch, error) {
 	return ret, c.e
 }
 
+// fork creates a cursor for running a sibling task concurrently. It
+// shares the budgets with c, and prints into its own buffer.
+func (c *cursor) fork() (*cursor, *bytes.Buffer) {
+	buf := new(bytes.Buffer)
+	ret := new(cursor)
+	*ret = *c
+
+	ret.stack = newStack()
+	ret.depth = c.depth + c.Len()
+	ret.cancel = nil // owned by c
+	ret.Printer = &Printer{
+		Prefix: c.Prefix,
+		Indent: c.Indent,
+		Shift:  c.Shift,
+		Writer: buf,
+	}
+
+	return ret, buf
+}
+
+// Ts runs sibling tasks concurrently with the cursor. The branches
+// are added, and the logs are printed, in the order of the tasks as
+// if they were run one after another.
+func (c *cursor) Ts(ts ...Task) ([]*Branch, error) {
+	if c.stopped() {
+		return nil, c.e
+	}
+
+	ret := make([]*Branch, len(ts))
+	forks := make([]*cursor, len(ts))
+	bufs := make([]*bytes.Buffer, len(ts))
+
+	var wg sync.WaitGroup
+	for i, t := range ts {
+		forks[i], bufs[i] = c.fork()
+		wg.Add(1)
+		go func(i int, t Task) {
+			defer wg.Done()
+			ret[i], _ = forks[i].T(t)
+		}(i, t)
+	}
+	wg.Wait()
+
+	for i, f := range forks {
+		if c.Printer.Error == nil {
+			_, c.Printer.Error = c.Writer.Write(bufs[i].Bytes())
+		}
+		if ret[i] != nil {
+			c.TopAdd(ret[i])
+		}
+		if c.e == nil && f.e != nil {
+			c.e = f.e
+		}
+	}
+
+	return ret, c.e
+}
+
 func (c *cursor) q(q *Query) *Leaf {
+	if q.Edns == nil && c.Edns != nil || !q.Use0x20 && c.Use0x20 {
+		cp := *q
+		if cp.Edns == nil {
+			cp.Edns = c.Edns
+		}
+		cp.Use0x20 = cp.Use0x20 || c.Use0x20
+		q = &cp
+	}
+
 	qp := &QueryPrinter{
 		Query:     q,
 		Printer:   c.Printer,
 		PrintFlag: c.PrintFlag,
 	}
 
-	ret := newLeaf(c.Retry)
+	n := c.Retry.sameServer()
+	ret := newLeaf(n)
 
-	for i := 0; i < c.Retry; i++ {
+	for i := 0; i < n; i++ {
 		if i > 0 {
-			c.Print("// retry")
+			c.Printf("// retry after %s", EndString(ret.LastEnd()))
+			if !c.sleep(c.Retry.wait(i)) {
+				break
+			}
+		}
+		answer := c.client.QueryContext(c.ctx, qp)
+		if answer.Error == errCaseMismatch {
+			// the server is suspected, and the retry in plain names
+			// verifies it
+			ret.add(answer, EndError)
+			c.Printf("// case mismatch, retry without 0x20")
+			answer = c.client.QueryContext(c.ctx, qp)
+		}
+		end := c.Retry.end(answer)
+		ret.add(answer, end)
+		if answer.Error == ErrCanceled {
+			break
 		}
-		answer := c.client.Query(qp)
-		ret.add(answer)
-		if answer.Timeout() {
+		if end == EndTimeout || end == EndError {
 			continue
 		}
 		break
@@ -113,3 +311,23 @@ func (c *cursor) q(q *Query) *Leaf {
 
 	return ret
 }
+
+// sleep waits for d, and returns false if the context is canceled
+// before that.
+func (c *cursor) sleep(d time.Duration) bool {
+	return sleepContext(c.ctx, d)
+}
+
+// sleepContext waits for d, and returns false if ctx is done before
+// that.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,279 @@
+package dns8
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Denials that leave the answer insecure rather than bogus.
+var (
+	errOptOut      = errors.New("nsec3 opt-out")
+	errNsec3Expand = errors.New("nsec3 iterations too many")
+)
+
+// maxNsec3Iterations is the limit of RFC 9276, over which the denial
+// is treated as insecure.
+const maxNsec3Iterations = 150
+
+// canonicalCompare compares two domains in the canonical order of
+// RFC 4034 section 6.1, which compares the labels from the right in
+// lower cases.
+func canonicalCompare(a, b *Domain) int {
+	i, j := len(a.labels)-1, len(b.labels)-1
+	for ; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		c := strings.Compare(strings.ToLower(a.labels[i]),
+			strings.ToLower(b.labels[j]))
+		if c != 0 {
+			return c
+		}
+	}
+	return len(a.labels) - len(b.labels)
+}
+
+// commonAncestor returns the closest common ancestor of two domains.
+func commonAncestor(a, b *Domain) *Domain {
+	n := 0
+	i, j := len(a.labels)-1, len(b.labels)-1
+	for ; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if !strings.EqualFold(a.labels[i], b.labels[j]) {
+			break
+		}
+		n++
+	}
+	return a.ancestor(n)
+}
+
+// ancestor returns the ancestor of the domain with n labels.
+func (d *Domain) ancestor(n int) *Domain {
+	labels := d.labels[len(d.labels)-n:]
+	return &Domain{strings.Join(labels, "."), labels}
+}
+
+// wildcard returns the wildcard domain under d.
+func (d *Domain) wildcard() *Domain {
+	labels := append([]string{"*"}, d.labels...)
+	return &Domain{strings.Join(labels, "."), labels}
+}
+
+// nsecCovers checks if the NSEC record proves that name does not exist,
+// where name is between the owner and the next domain. The last record
+// of a zone wraps around to the apex.
+func nsecCovers(rr *RR, name *Domain) bool {
+	next := rr.Rdata.(*RdNsec).NextDomain
+	after := canonicalCompare(rr.Domain, name) < 0
+	before := canonicalCompare(name, next) < 0
+	if canonicalCompare(rr.Domain, next) < 0 {
+		return after && before
+	}
+	return after || before
+}
+
+// noType checks if the types of a name prove the absence of type t.
+func noType(types []uint16, t uint16) bool {
+	return !hasType(types, t) && !hasType(types, CNAME)
+}
+
+// denyNsec checks the NSEC records for the proof that the name does not
+// exist when nx is true, or the name has no records of type t.
+func denyNsec(nsecs []*RR, name *Domain, t uint16, nx bool) error {
+	if !nx {
+		for _, rr := range nsecs {
+			if rr.Domain.Equal(name) {
+				if noType(rr.Rdata.(*RdNsec).Types, t) {
+					return nil
+				}
+				return fmt.Errorf("nsec of %v lists the type", name)
+			}
+		}
+	}
+
+	var cover *RR
+	for _, rr := range nsecs {
+		if nsecCovers(rr, name) {
+			cover = rr
+			break
+		}
+	}
+	if cover == nil {
+		return fmt.Errorf("no nsec covers %v", name)
+	}
+
+	// an empty non-terminal has no nsec, but names under it follow
+	next := cover.Rdata.(*RdNsec).NextDomain
+	if !nx && name.IsParentOf(next) {
+		return nil
+	}
+
+	// the closest encloser, which has no wildcard child of type t
+	ce := commonAncestor(name, cover.Domain)
+	if c := commonAncestor(name, next); len(c.labels) > len(ce.labels) {
+		ce = c
+	}
+	wild := ce.wildcard()
+	for _, rr := range nsecs {
+		if rr.Domain.Equal(wild) {
+			return denyWildcard(wild, rr.Rdata.(*RdNsec).Types, t, nx)
+		}
+	}
+	for _, rr := range nsecs {
+		if nsecCovers(rr, wild) {
+			return denyNoWildcard(name, nx)
+		}
+	}
+	return fmt.Errorf("no nsec covers %v", wild)
+}
+
+// denyWildcard checks the proof when the wildcard of the closest
+// encloser exists, which only proves that it has no type t.
+func denyWildcard(wild *Domain, types []uint16, t uint16, nx bool) error {
+	if !nx && noType(types, t) {
+		return nil
+	}
+	return fmt.Errorf("wildcard %v exists", wild)
+}
+
+// denyNoWildcard checks the proof when the name does not exist, and
+// neither does the wildcard.
+func denyNoWildcard(name *Domain, nx bool) error {
+	if nx {
+		return nil
+	}
+	return fmt.Errorf("%v does not exist, but no name error", name)
+}
+
+// nsec3HashName hashes a name with the NSEC3 parameters, RFC 5155.
+func nsec3HashName(d *Domain, salt []byte, iterations uint16) []byte {
+	buf := new(bytes.Buffer)
+	d.canonical().Pack(buf)
+	h := sha1.Sum(append(buf.Bytes(), salt...))
+	for i := 0; i < int(iterations); i++ {
+		h = sha1.Sum(append(h[:], salt...))
+	}
+	return h[:]
+}
+
+// nsec3Chain is a set of NSEC3 records of a zone.
+type nsec3Chain struct {
+	zone   *Domain
+	param  *RdNsec3Param
+	owners [][]byte // hashed owners
+	rds    []*RdNsec3
+}
+
+func newNsec3Chain(zone *Domain, rrs []*RR) (*nsec3Chain, error) {
+	ret := &nsec3Chain{zone: zone}
+	for _, rr := range rrs {
+		rd := rr.Rdata.(*RdNsec3)
+		if !rr.Domain.Parent().Equal(zone) {
+			return nil, fmt.Errorf("nsec3 %v not of the zone", rr.Domain)
+		}
+		owner, e := nsec3Hash.DecodeString(strings.ToUpper(rr.Domain.labels[0]))
+		if e != nil {
+			return nil, fmt.Errorf("invalid nsec3 owner %v", rr.Domain)
+		}
+
+		if ret.param == nil {
+			ret.param = rd.param()
+		} else if rd.HashAlgorithm != ret.param.HashAlgorithm ||
+			rd.Iterations != ret.param.Iterations ||
+			!bytes.Equal(rd.Salt, ret.param.Salt) {
+			return nil, errors.New("nsec3 parameters differ")
+		}
+		ret.owners = append(ret.owners, owner)
+		ret.rds = append(ret.rds, rd)
+	}
+
+	if ret.param.HashAlgorithm != 1 {
+		return nil, fmt.Errorf("nsec3 hash %d not supported",
+			ret.param.HashAlgorithm)
+	}
+	if ret.param.Iterations > maxNsec3Iterations {
+		return nil, errNsec3Expand
+	}
+	return ret, nil
+}
+
+func (c *nsec3Chain) hash(d *Domain) []byte {
+	return nsec3HashName(d, c.param.Salt, c.param.Iterations)
+}
+
+// match returns the NSEC3 record of the name, nil if none.
+func (c *nsec3Chain) match(d *Domain) *RdNsec3 {
+	h := c.hash(d)
+	for i, owner := range c.owners {
+		if bytes.Equal(owner, h) {
+			return c.rds[i]
+		}
+	}
+	return nil
+}
+
+// cover returns the NSEC3 record that proves the name does not exist,
+// nil if none.
+func (c *nsec3Chain) cover(d *Domain) *RdNsec3 {
+	h := c.hash(d)
+	for i, owner := range c.owners {
+		next := c.rds[i].NextHashed
+		after := bytes.Compare(owner, h) < 0
+		before := bytes.Compare(h, next) < 0
+		if bytes.Compare(owner, next) < 0 && after && before ||
+			bytes.Compare(owner, next) >= 0 && (after || before) {
+			return c.rds[i]
+		}
+	}
+	return nil
+}
+
+// closestEncloser finds the closest encloser of the name that exists,
+// and the NSEC3 record that covers the next closer name.
+func (c *nsec3Chain) closestEncloser(name *Domain) (*Domain, *RdNsec3) {
+	for d := name; d != nil && c.zone.IsZoneOf(d); d = d.Parent() {
+		if d.Equal(name) || c.match(d) == nil {
+			continue
+		}
+		next := name.ancestor(len(d.labels) + 1)
+		return d, c.cover(next)
+	}
+	return nil, nil
+}
+
+// denyNsec3 checks the NSEC3 records for the proof that the name does
+// not exist when nx is true, or the name has no records of type t.
+func denyNsec3(zone *Domain, rrs []*RR, name *Domain, t uint16, nx bool) error {
+	c, e := newNsec3Chain(zone, rrs)
+	if e != nil {
+		return e
+	}
+
+	if !nx {
+		if rd := c.match(name); rd != nil {
+			if noType(rd.Types, t) {
+				return nil
+			}
+			return fmt.Errorf("nsec3 of %v lists the type", name)
+		}
+	}
+
+	ce, cover := c.closestEncloser(name)
+	if ce == nil {
+		return fmt.Errorf("no closest encloser of %v", name)
+	}
+	if cover == nil {
+		return fmt.Errorf("no nsec3 covers the next closer of %v", name)
+	}
+	if !nx && t == DS && cover.OptOut() {
+		return errOptOut
+	}
+
+	wild := ce.wildcard()
+	if rd := c.match(wild); rd != nil {
+		return denyWildcard(wild, rd.Types, t, nx)
+	}
+	if c.cover(wild) == nil {
+		return fmt.Errorf("no nsec3 covers %v", wild)
+	}
+	return denyNoWildcard(name, nx)
+}
@@ -0,0 +1,305 @@
+package dns8
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DNSSEC algorithms, RFC 8624
+const (
+	AlgRSASHA1         = 5
+	AlgRSASHA1NSEC3    = 7
+	AlgRSASHA256       = 8
+	AlgRSASHA512       = 10
+	AlgECDSAP256SHA256 = 13
+	AlgECDSAP384SHA384 = 14
+	AlgED25519         = 15
+)
+
+// DS digest types
+const (
+	DigestSHA1   = 1
+	DigestSHA256 = 2
+	DigestSHA384 = 4
+)
+
+// algSupported checks if signatures of the algorithm can be verified.
+func algSupported(alg uint8) bool {
+	switch alg {
+	case AlgRSASHA1, AlgRSASHA1NSEC3, AlgRSASHA256, AlgRSASHA512,
+		AlgECDSAP256SHA256, AlgECDSAP384SHA384, AlgED25519:
+		return true
+	}
+	return false
+}
+
+func digest(digestType uint8, data []byte) []byte {
+	switch digestType {
+	case DigestSHA1:
+		h := sha1.Sum(data)
+		return h[:]
+	case DigestSHA256:
+		h := sha256.Sum256(data)
+		return h[:]
+	case DigestSHA384:
+		h := sha512.Sum384(data)
+		return h[:]
+	}
+	return nil
+}
+
+// KeyTag computes the key tag of the key, RFC 4034 appendix B.
+func (d *RdDnskey) KeyTag() uint16 {
+	var ac uint32
+	for i, b := range d.Pack() {
+		if i&1 == 0 {
+			ac += uint32(b) << 8
+		} else {
+			ac += uint32(b)
+		}
+	}
+	ac += ac >> 16 & 0xffff
+	return uint16(ac)
+}
+
+// DS makes the DS record of the key of the zone, with the digest type.
+// It returns nil if the digest type is not supported.
+func (d *RdDnskey) DS(zone *Domain, digestType uint8) *RdDs {
+	buf := new(bytes.Buffer)
+	zone.canonical().Pack(buf)
+	buf.Write(d.Pack())
+
+	h := digest(digestType, buf.Bytes())
+	if h == nil {
+		return nil
+	}
+	return &RdDs{
+		KeyTag:     d.KeyTag(),
+		Algorithm:  d.Algorithm,
+		DigestType: digestType,
+		Digest:     h,
+	}
+}
+
+// matches checks if the key is the one of the DS record.
+func (d *RdDnskey) matches(zone *Domain, ds *RdDs) bool {
+	if d.Algorithm != ds.Algorithm || d.KeyTag() != ds.KeyTag {
+		return false
+	}
+	mine := d.DS(zone, ds.DigestType)
+	return mine != nil && bytes.Equal(mine.Digest, ds.Digest)
+}
+
+// signedData makes the data that a signature signs over a record set,
+// in the canonical form of RFC 4034 section 6.
+func signedData(sig *RdRrsig, rrset []*RR) []byte {
+	buf := new(bytes.Buffer)
+	sig.packHead(buf)
+
+	owner := rrset[0].Domain.canonical()
+	if n := int(sig.Labels); n < len(owner.labels) {
+		labels := append([]string{"*"}, owner.labels[len(owner.labels)-n:]...)
+		owner = &Domain{strings.Join(labels, "."), labels}
+	}
+
+	head := new(bytes.Buffer)
+	owner.Pack(head)
+	b := make([]byte, 8)
+	enc.PutUint16(b[0:2], rrset[0].Type)
+	enc.PutUint16(b[2:4], rrset[0].Class)
+	enc.PutUint32(b[4:8], sig.OrigTTL)
+	head.Write(b)
+
+	rdatas := make([][]byte, len(rrset))
+	for i, rr := range rrset {
+		rdatas[i] = canonicalRdata(rr.Rdata)
+	}
+	sort.Slice(rdatas, func(i, j int) bool {
+		return bytes.Compare(rdatas[i], rdatas[j]) < 0
+	})
+
+	for i, rd := range rdatas {
+		if i > 0 && bytes.Equal(rd, rdatas[i-1]) {
+			continue // duplicates are removed
+		}
+		buf.Write(head.Bytes())
+		enc.PutUint16(b[0:2], uint16(len(rd)))
+		buf.Write(b[0:2])
+		buf.Write(rd)
+	}
+
+	return buf.Bytes()
+}
+
+// canonicalRdata packs the rdata with the names in lower cases, for
+// the types listed in RFC 4034 section 6.2. The signer name of RRSIG
+// and the next domain of NSEC are kept as is, RFC 6840 section 5.1.
+func canonicalRdata(rd Rdata) []byte {
+	switch rd := rd.(type) {
+	case *RdDomain:
+		return (*RdDomain)((*Domain)(rd).canonical()).Pack()
+	case *RdMx:
+		cp := *rd
+		cp.Domain = lowerLabels(rd.Domain)
+		return cp.Pack()
+	case *RdSoa:
+		cp := *rd
+		cp.Mname = lowerLabels(rd.Mname)
+		cp.Rname = lowerLabels(rd.Rname)
+		return cp.Pack()
+	case *RdSrv:
+		cp := *rd
+		cp.Target = rd.Target.canonical()
+		return cp.Pack()
+	case *RdNaptr:
+		cp := *rd
+		cp.Replacement = rd.Replacement.canonical()
+		return cp.Pack()
+	}
+	return rd.Pack()
+}
+
+// rsaKey parses an RSA public key in the format of RFC 3110.
+func rsaKey(bs []byte) (*rsa.PublicKey, error) {
+	if len(bs) < 1 {
+		return nil, errors.New("empty rsa key")
+	}
+	n := int(bs[0])
+	bs = bs[1:]
+	if n == 0 {
+		if len(bs) < 2 {
+			return nil, errors.New("rsa key truncated")
+		}
+		n = int(enc.Uint16(bs))
+		bs = bs[2:]
+	}
+	if len(bs) <= n {
+		return nil, errors.New("rsa key truncated")
+	}
+
+	exp := new(big.Int).SetBytes(bs[:n])
+	if !exp.IsInt64() || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("rsa exponent too large")
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(bs[n:]),
+		E: int(exp.Int64()),
+	}, nil
+}
+
+// verify verifies the signature over the data with the key.
+func verify(key *RdDnskey, data, sig []byte) error {
+	var hash crypto.Hash
+	switch key.Algorithm {
+	case AlgRSASHA1, AlgRSASHA1NSEC3:
+		hash = crypto.SHA1
+	case AlgRSASHA256, AlgECDSAP256SHA256:
+		hash = crypto.SHA256
+	case AlgRSASHA512:
+		hash = crypto.SHA512
+	case AlgECDSAP384SHA384:
+		hash = crypto.SHA384
+	case AlgED25519:
+		if len(key.PublicKey) != ed25519.PublicKeySize {
+			return errors.New("invalid ed25519 key")
+		}
+		if !ed25519.Verify(key.PublicKey, data, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("algorithm %d not supported", key.Algorithm)
+	}
+
+	h := hash.New()
+	h.Write(data)
+	hashed := h.Sum(nil)
+
+	switch key.Algorithm {
+	case AlgECDSAP256SHA256, AlgECDSAP384SHA384:
+		curve := elliptic.P256()
+		if key.Algorithm == AlgECDSAP384SHA384 {
+			curve = elliptic.P384()
+		}
+		n := curve.Params().BitSize / 8
+		if len(key.PublicKey) != 2*n || len(sig) != 2*n {
+			return errors.New("invalid ecdsa key or signature")
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(key.PublicKey[:n]),
+			Y:     new(big.Int).SetBytes(key.PublicKey[n:]),
+		}
+		r := new(big.Int).SetBytes(sig[:n])
+		s := new(big.Int).SetBytes(sig[n:])
+		if !ecdsa.Verify(pub, hashed, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+
+	pub, e := rsaKey(key.PublicKey)
+	if e != nil {
+		return e
+	}
+	return rsa.VerifyPKCS1v15(pub, hash, hashed, sig)
+}
+
+// verifyRRset checks that one of the signatures is valid for the
+// record set, signed by one of the keys of the zone, and returns the
+// valid signature.
+func verifyRRset(rrset, sigs []*RR, zone *Domain, keys []*RdDnskey) (
+	*RdRrsig, error,
+) {
+	if len(rrset) == 0 {
+		return nil, errors.New("empty record set")
+	}
+	if len(sigs) == 0 {
+		return nil, errors.New("no rrsig")
+	}
+
+	now := time.Now().Unix()
+	owner := rrset[0].Domain
+	e := errors.New("no key for rrsig")
+	for _, rr := range sigs {
+		sig := rr.Rdata.(*RdRrsig)
+		switch {
+		case !sig.SignerName.Equal(zone):
+			e = fmt.Errorf("rrsig signed by %v", sig.SignerName)
+			continue
+		case int(sig.Labels) > len(owner.labels):
+			e = errors.New("rrsig labels too many")
+			continue
+		case now < int64(sig.Inception):
+			e = errors.New("rrsig not yet valid")
+			continue
+		case now > int64(sig.Expiration):
+			e = errors.New("rrsig expired")
+			continue
+		}
+
+		data := signedData(sig, rrset)
+		for _, key := range keys {
+			if key.Algorithm != sig.Algorithm || key.KeyTag() != sig.KeyTag {
+				continue
+			}
+			if e = verify(key, data, sig.Signature); e == nil {
+				return sig, nil
+			}
+		}
+	}
+	return nil, e
+}
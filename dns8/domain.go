@@ -8,7 +8,9 @@ import (
 	"strings"
 )
 
-// Domain saves a domain name
+// Domain saves a domain name. A domain unpacked from a packet keeps
+// the letter cases in it, and the names are compared regardless of
+// the cases.
 type Domain struct {
 	name   string
 	labels []string
@@ -27,13 +29,31 @@ func (d *Domain) Equal(o *Domain) bool {
 	}
 
 	for i, lab := range d.labels {
-		if o.labels[i] != lab {
+		if !strings.EqualFold(o.labels[i], lab) {
 			return false
 		}
 	}
 	return true
 }
 
+// key returns the name in lower cases, for mapping the names
+// regardless of the cases.
+func (d *Domain) key() string { return strings.ToLower(d.String()) }
+
+// canonical returns the domain in lower cases, as in the canonical
+// form of RFC 4034 section 6.2.
+func (d *Domain) canonical() *Domain {
+	return &Domain{strings.ToLower(d.name), lowerLabels(d.labels)}
+}
+
+func lowerLabels(labels []string) []string {
+	ret := make([]string, len(labels))
+	for i, lab := range labels {
+		ret[i] = strings.ToLower(lab)
+	}
+	return ret
+}
+
 // String returns the domain name string representation
 func (d *Domain) String() string {
 	if d.IsRoot() {
@@ -115,7 +135,7 @@ func (d *Domain) IsChildOf(c *Domain) bool {
 
 	delta := n - nc
 	for i, lab := range c.labels {
-		if d.labels[i+delta] != lab {
+		if !strings.EqualFold(d.labels[i+delta], lab) {
 			return false
 		}
 	}
@@ -154,13 +174,13 @@ func (d *Domain) RegParts() (registered *Domain, registrar *Domain) {
 			// top level domain
 			return last, cur
 		}
-		if superRegs[cur.name] {
+		if superRegs[cur.key()] {
 			return last, cur
 		}
-		if superRegs[parent.name] && nonRegs[cur.name] {
+		if superRegs[parent.key()] && nonRegs[cur.key()] {
 			return last, cur
 		}
-		if regs[cur.name] {
+		if regs[cur.key()] {
 			return last, cur
 		}
 
@@ -201,7 +221,7 @@ func UnpackDomain(buf *bytes.Reader, p []byte) (*Domain, error) {
 	}
 
 	for _, lab := range labels {
-		if e := checkLabel(lab); e != nil {
+		if e := checkLabel(strings.ToLower(lab)); e != nil {
 			return nil, e
 		}
 	}
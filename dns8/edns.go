@@ -0,0 +1,89 @@
+package dns8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// EDNS0 defaults
+const (
+	EdnsUDPSize = 4096 // the default advertised UDP payload size
+	ednsDO      = 0x1 << 15
+)
+
+// Edns is the EDNS0 setting that is carried in an OPT pseudo-record.
+type Edns struct {
+	UDPSize  uint16 // advertised UDP payload size
+	ExtRcode uint8  // upper 8 bits of the extended rcode
+	Version  uint8
+	DO       bool // DNSSEC OK
+	Options  []*EdnsOption
+}
+
+// NewEdns creates an EDNS0 setting with the default payload size.
+func NewEdns() *Edns {
+	return &Edns{UDPSize: EdnsUDPSize}
+}
+
+// RR converts the setting into an OPT pseudo-record.
+func (e *Edns) RR() *RR {
+	ttl := uint32(e.ExtRcode)<<24 | uint32(e.Version)<<16
+	if e.DO {
+		ttl |= ednsDO
+	}
+
+	return &RR{
+		Domain: Root,
+		Type:   OPT,
+		Class:  e.UDPSize,
+		TTL:    ttl,
+		Rdata:  RdOpt(e.Options),
+	}
+}
+
+// RRToEdns converts an OPT pseudo-record into an EDNS0 setting.
+// It returns nil if the record is not an OPT record.
+func RRToEdns(rr *RR) *Edns {
+	if rr.Type != OPT {
+		return nil
+	}
+
+	ret := &Edns{
+		UDPSize:  rr.Class,
+		ExtRcode: uint8(rr.TTL >> 24),
+		Version:  uint8(rr.TTL >> 16),
+		DO:       rr.TTL&ednsDO != 0,
+	}
+	if opts, ok := rr.Rdata.(RdOpt); ok {
+		ret.Options = opts
+	}
+
+	return ret
+}
+
+// Option returns the first option with code, nil if not found.
+func (e *Edns) Option(code uint16) *EdnsOption {
+	for _, opt := range e.Options {
+		if opt.Code == code {
+			return opt
+		}
+	}
+	return nil
+}
+
+func (e *Edns) String() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "opt udp=%d ver=%d", e.UDPSize, e.Version)
+	if e.ExtRcode != 0 {
+		fmt.Fprintf(buf, " ext-rcode=%d", e.ExtRcode)
+	}
+	if e.DO {
+		fmt.Fprint(buf, " do")
+	}
+	if len(e.Options) > 0 {
+		fmt.Fprint(buf, " ")
+		RdOpt(e.Options).PrintTo(buf)
+	}
+
+	return buf.String()
+}
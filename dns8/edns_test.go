@@ -0,0 +1,82 @@
+package dns8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEdnsPack(t *testing.T) {
+	edns := NewEdns()
+	edns.DO = true
+	edns.Options = []*EdnsOption{
+		{Code: OptNSID},
+		{Code: OptCookie, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+	}
+
+	q := QpackEdns(D("lonnie.io"), A, 1234, edns)
+	p, e := Unpack(q.Bytes)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	got := p.Edns()
+	if got == nil {
+		t.Fatal("opt record missing")
+	}
+	if got.UDPSize != EdnsUDPSize || !got.DO || got.Version != 0 {
+		t.Errorf("wrong edns: %v", got)
+	}
+	if len(got.Options) != 2 {
+		t.Fatalf("expect 2 options, got %d", len(got.Options))
+	}
+	if o := got.Option(OptCookie); o == nil ||
+		!bytes.Equal(o.Data, edns.Options[1].Data) {
+		t.Errorf("wrong cookie: %v", o)
+	}
+	if !bytes.Equal(p.PackQuery(), q.Bytes) {
+		t.Error("repack mismatch")
+	}
+
+	p.Addition[0].TTL |= 1 << 24
+	if p.Rcode() != 1<<4 {
+		t.Errorf("extended rcode, expect %d, got %d", 1<<4, p.Rcode())
+	}
+}
+
+func TestEdnsTruncated(t *testing.T) {
+	edns := NewEdns()
+	edns.ExtRcode = 1
+	p := &Packet{
+		ID:        1234,
+		Flag:      FlagResponse | FlagTC,
+		Question:  &Question{D("lonnie.io"), TXT, IN},
+		Authority: Section{rrNS("lonnie.io", "ns.lonnie.io")},
+		Addition:  Section{rrA("ns.lonnie.io", "10.0.0.1"), edns.RR()},
+	}
+
+	for _, test := range []struct {
+		cut    int // bytes cut from the end
+		expect bool
+	}{
+		{0, true},
+		{11, false}, // the opt record is cut off
+	} {
+		bs := p.Pack()
+		got, e := Unpack(bs[:len(bs)-test.cut])
+		if e != nil {
+			t.Fatal(e)
+		}
+		if len(got.Authority) != 0 {
+			t.Errorf("cut %d: expect no authority, got %v",
+				test.cut, got.Authority)
+		}
+		if (got.Edns() != nil) != test.expect {
+			t.Errorf("cut %d: expect opt %v, got %v",
+				test.cut, test.expect, got.Addition)
+		}
+		if test.expect && got.Rcode() != 1<<4 {
+			t.Errorf("cut %d: expect rcode %d, got %d",
+				test.cut, 1<<4, got.Rcode())
+		}
+	}
+}
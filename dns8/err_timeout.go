@@ -5,3 +5,9 @@ import (
 )
 
 var errTimeout = errors.New("timeout")
+
+// ErrCanceled is the error of the exchanges and the query trees
+// that are stopped by a canceled context.
+var ErrCanceled = errors.New("canceled")
+
+var errCaseMismatch = errors.New("query name case mismatch")
@@ -2,6 +2,7 @@ package dns8
 
 import (
 	"fmt"
+	"time"
 )
 
 // Exchange is the packet exchange for a query.
@@ -12,6 +13,22 @@ type Exchange struct {
 	Recv      *Message
 	Error     error
 	PrintFlag int
+
+	TCP    bool          // if the reply was truncated and retried over TCP
+	Queued time.Duration // time waited for the rate limit before sending
+	Mixed  bool          // if the query name was sent in 0x20 mixed cases
+
+	// Coalesced is set if the query is not sent, but shares the
+	// reply of an identical outstanding query.
+	Coalesced bool
+
+	// Cached is set if the query is not sent, but answered from the
+	// record cache. Send is nil then.
+	Cached bool
+
+	// Local is set if the query is not sent, but answered from the
+	// local root zone. Send is nil then.
+	Local bool
 }
 
 // PrintTo prints the exchange to a printer
@@ -26,10 +43,12 @@ func (x *Exchange) printSend(p *Printer) {
 
 	switch x.PrintFlag {
 	case PrintAll:
-		p.Print("send {")
-		p.ShiftIn()
-		x.Send.PrintTo(p)
-		p.ShiftOut("}")
+		if x.Send != nil {
+			p.Print("send {")
+			p.ShiftIn()
+			x.Send.PrintTo(p)
+			p.ShiftOut("}")
+		}
 	case PrintReply:
 		// do nothing
 	default:
@@ -37,24 +56,41 @@ func (x *Exchange) printSend(p *Printer) {
 	}
 }
 
-func (x *Exchange) printTimeTaken(p *Printer) {
-	d := x.Recv.Timestamp.Sub(x.Send.Timestamp)
+func durationStr(d time.Duration) string {
 	n := d.Nanoseconds()
-	var s string
 	if n < 1e3 {
-		s = fmt.Sprintf("%dns", n)
+		return fmt.Sprintf("%dns", n)
 	} else if n < 1e6 {
-		s = fmt.Sprintf("%.1fus", float64(n)/1e3)
+		return fmt.Sprintf("%.1fus", float64(n)/1e3)
 	} else if n < 1e9 {
-		s = fmt.Sprintf("%.2fms", float64(n)/1e6)
-	} else {
-		s = fmt.Sprintf("%.3fs", float64(n)/1e9)
+		return fmt.Sprintf("%.2fms", float64(n)/1e6)
 	}
+	return fmt.Sprintf("%.3fs", float64(n)/1e9)
+}
 
-	p.Printf("(in %v)", s)
+func (x *Exchange) printTimeTaken(p *Printer) {
+	s := durationStr(x.Recv.Timestamp.Sub(x.Send.Timestamp))
+	if x.Queued > 0 {
+		p.Printf("(in %v, queued %v)", s, durationStr(x.Queued))
+	} else {
+		p.Printf("(in %v)", s)
+	}
 }
 
 func (x *Exchange) printRecv(p *Printer) {
+	if x.Coalesced {
+		p.Print("// coalesced")
+	}
+	if x.Cached {
+		p.Print("// cached")
+	}
+	if x.Local {
+		p.Print("// local")
+	}
+	if x.TCP {
+		p.Print("// truncated, retry with tcp")
+	}
+
 	switch x.PrintFlag {
 	case PrintAll:
 		if x.Recv != nil {
@@ -70,7 +106,9 @@ func (x *Exchange) printRecv(p *Printer) {
 	case PrintReply:
 		if x.Recv != nil {
 			x.Recv.Packet.PrintTo(p)
-			x.printTimeTaken(p)
+			if x.Send != nil {
+				x.printTimeTaken(p)
+			}
 		}
 		if x.Error != nil {
 			p.Printf("error %v", x.Error)
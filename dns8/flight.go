@@ -0,0 +1,191 @@
+package dns8
+
+import (
+	"sync"
+)
+
+// flightKey identifies the identical queries that can share a reply.
+type flightKey struct {
+	domain  string
+	typ     uint16
+	server  string
+	edns    string
+	use0x20 bool
+}
+
+func keyOfQuery(q *Query) flightKey {
+	ret := flightKey{
+		domain:  q.Domain.key(),
+		typ:     q.Type,
+		server:  q.Server.String(),
+		use0x20: q.Use0x20,
+	}
+	if q.Edns != nil {
+		ret.edns = q.Edns.String()
+	}
+	return ret
+}
+
+// waiter is a query waiting for the reply of a flight.
+type waiter struct {
+	*QueryPrinter
+	c chan<- *Exchange
+
+	flight    *flight
+	coalesced bool // if it joined a flight of another query
+	sent      bool // if the send is printed
+}
+
+// flight is an outstanding query, which is shared by all the
+// identical queries sent before the reply.
+type flight struct {
+	key       flightKey
+	waiters   []*waiter
+	job       *job // nil when not sent yet
+	abandoned bool // all the waiters have left
+}
+
+// flights are the outstanding queries of a client.
+type flights struct {
+	lock sync.Mutex
+	m    map[flightKey]*flight
+}
+
+func newFlights() *flights {
+	ret := new(flights)
+	ret.m = make(map[flightKey]*flight)
+	return ret
+}
+
+// join joins the flight of an identical query, or starts a new flight
+// if there is none. It returns the waiter, and if it is a new flight.
+func (fs *flights) join(q *QueryPrinter, ch chan<- *Exchange) (*waiter, bool) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	w := &waiter{QueryPrinter: q, c: ch}
+	key := keyOfQuery(q.Query)
+	f := fs.m[key]
+	if f != nil {
+		w.coalesced = true
+	} else {
+		f = &flight{key: key}
+		fs.m[key] = f
+	}
+
+	w.flight = f
+	f.waiters = append(f.waiters, w)
+	if f.job != nil {
+		w.printSend(f.job.exchange)
+	}
+	return w, !w.coalesced
+}
+
+// leave takes a waiter off its flight. It returns true if the waiter
+// is taken off before the reply, and the caller should end it. When
+// the waiter is the last one of a flight that is sent, the flight is
+// abandoned, and the job is returned for canceling, which ends the
+// waiter with the cancel error.
+func (fs *flights) leave(w *waiter) (bool, *job) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	f := w.flight
+	index := -1
+	for i, other := range f.waiters {
+		if other == w {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return false, nil // got the reply already
+	}
+
+	if len(f.waiters) == 1 {
+		f.abandoned = true
+		fs.remove(f)
+		if f.job != nil {
+			return false, f.job
+		}
+	}
+
+	f.waiters = append(f.waiters[:index], f.waiters[index+1:]...)
+	return true, nil
+}
+
+// send sets the job of a flight, and prints the send for the waiters.
+// It returns false if the flight is abandoned and should not be sent.
+func (fs *flights) send(f *flight, j *job) bool {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	f.job = j
+	if f.abandoned {
+		return false
+	}
+	for _, w := range f.waiters {
+		w.printSend(j.exchange)
+	}
+	return true
+}
+
+// abandoned checks if all the waiters of a flight have left.
+func (fs *flights) abandoned(f *flight) bool {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	return f.abandoned
+}
+
+func (fs *flights) remove(f *flight) {
+	if fs.m[f.key] == f {
+		delete(fs.m, f.key)
+	}
+}
+
+// exchange makes a copy of the exchange of a flight for the waiter.
+func (w *waiter) exchange(x *Exchange) *Exchange {
+	cp := *x
+	cp.Query = w.Query
+	cp.PrintFlag = w.PrintFlag
+	cp.Coalesced = w.coalesced
+	return &cp
+}
+
+// printSend prints the send of the flight for the waiter. It is called
+// with the lock held, so that it comes before the recv. Only the send
+// message is read, as the rest of the exchange is set when the reply
+// lands.
+func (w *waiter) printSend(x *Exchange) {
+	if w.Printer != nil {
+		sent := &Exchange{
+			Query:     w.Query,
+			Send:      x.Send,
+			PrintFlag: w.PrintFlag,
+		}
+		sent.printSend(w.Printer)
+	}
+	w.sent = true
+}
+
+// end gives the exchange to the waiter, and prints the recv if the
+// send is printed.
+func (w *waiter) end(x *Exchange) {
+	if w.Printer != nil && w.sent {
+		x.printRecv(w.Printer)
+	}
+	w.c <- x
+}
+
+// land ends a flight with the exchange, and gives each waiter a copy.
+func (fs *flights) land(f *flight, x *Exchange) {
+	fs.lock.Lock()
+	fs.remove(f)
+	waiters := f.waiters
+	f.waiters = nil
+	fs.lock.Unlock()
+
+	for _, w := range waiters {
+		w.end(w.exchange(x))
+	}
+}
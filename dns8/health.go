@@ -0,0 +1,75 @@
+package dns8
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultHalfLife is the default decay half life of server failures.
+const DefaultHalfLife = time.Minute
+
+// Health tracks the recent failures of name servers. The failure
+// score of a server decays by half every HalfLife, and is cleared
+// when the server replies. A Health can be shared by clients.
+type Health struct {
+	HalfLife time.Duration
+
+	lock   sync.Mutex
+	scores map[ipKey]*healthScore
+}
+
+type healthScore struct {
+	value float64
+	t     time.Time
+}
+
+// NewHealth creates a new health tracker.
+func NewHealth() *Health {
+	ret := new(Health)
+	ret.HalfLife = DefaultHalfLife
+	ret.scores = make(map[ipKey]*healthScore)
+	return ret
+}
+
+func (h *Health) decayed(s *healthScore, now time.Time) float64 {
+	if s == nil {
+		return 0
+	}
+	halves := float64(now.Sub(s.t)) / float64(h.HalfLife)
+	return s.value * math.Pow(0.5, halves)
+}
+
+// Fail records a failure of the server.
+func (h *Health) Fail(ip net.IP) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := time.Now()
+	k := keyOfIP(ip)
+	s := h.scores[k]
+	h.scores[k] = &healthScore{h.decayed(s, now) + 1, now}
+}
+
+// Succeed records that the server replied, and clears its failures.
+func (h *Health) Succeed(ip net.IP) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	delete(h.scores, keyOfIP(ip))
+}
+
+// Score returns the decayed failure score of the server.
+func (h *Health) Score(ip net.IP) float64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.decayed(h.scores[keyOfIP(ip)], time.Now())
+}
+
+// Healthy checks if the server has not failed recently.
+// A server that has failed once is unhealthy for one half life.
+func (h *Health) Healthy(ip net.IP) bool {
+	return h.Score(ip) < 0.5
+}
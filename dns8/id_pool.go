@@ -1,7 +1,7 @@
 package dns8
 
 import (
-	"math/rand"
+	"crypto/rand"
 )
 
 const (
@@ -15,7 +15,7 @@ type idPool struct {
 	nusing   int
 	returns  chan uint16
 	prepared chan uint16
-	rand     *rand.Rand
+	entropy  []byte
 }
 
 func newIDPool() *idPool {
@@ -24,18 +24,32 @@ func newIDPool() *idPool {
 	ret.returns = make(chan uint16, 10)
 	ret.prepared = make(chan uint16, nprepare)
 
-	// TODO: find a better random source
-	src := rand.NewSource(0)
-	ret.rand = rand.New(src)
-
 	go ret.serve()
 
 	return ret
 }
 
+// entropySize is the number of random bytes read at a time
+const entropySize = 1024
+
+// rand16 returns a cryptographically random id, so that the ids are
+// not predictable by an off-path attacker.
+func (p *idPool) rand16() uint16 {
+	if len(p.entropy) < 2 {
+		p.entropy = make([]byte, entropySize)
+		if _, e := rand.Read(p.entropy); e != nil {
+			panic(e)
+		}
+	}
+
+	ret := enc.Uint16(p.entropy)
+	p.entropy = p.entropy[2:]
+	return ret
+}
+
 func (p *idPool) pick() uint16 {
 	for {
-		ret := uint16(p.rand.Uint32())
+		ret := p.rand16()
 		if !p.using[ret] {
 			return ret
 		}
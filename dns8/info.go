@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 )
 
 // Info is a query task that gets all the related records.
@@ -14,6 +15,7 @@ type Info struct {
 	HeadLess   bool
 	Shallow    bool
 	HideResult bool
+	CAA        bool // also collect the CAA records of the registered domain
 
 	EndWith *ZoneServers
 
@@ -27,6 +29,8 @@ type Info struct {
 	NameServersMap map[string]*NameServer
 
 	Zones map[string]*ZoneServers
+
+	Caas []*RR // the CAA records in effect, when CAA
 }
 
 // NewInfo creates a query task that queries all the
@@ -105,8 +109,10 @@ func (info *Info) run(c Cursor) *IPs {
 
 	info.collectInfo(ips)
 
-	for _, z := range info.Zones {
-		info.queryZone(z, c)
+	info.queryZones(c)
+
+	if info.CAA {
+		info.queryCaa(c)
 	}
 
 	return ips
@@ -146,25 +152,65 @@ func (info *Info) _collectInfo(ips *IPs) {
 
 		info.appendAll(z.Records())
 
-		zoneStr := z.Zone().String()
+		zoneStr := z.Zone().key()
 		if info.Zones[zoneStr] == nil {
 			info.Zones[zoneStr] = z
 		}
 	}
 }
 
-func (info *Info) queryZone(z *ZoneServers, c Cursor) error {
-	for _, t := range infoTypes {
-		recur := NewRecurType(z.Zone(), t)
-		recur.StartWith = z
-		_, e := c.T(recur)
-		if e != nil {
-			return e
+// queryZones queries the info types of all the zones concurrently,
+// in the order of the zone names.
+func (info *Info) queryZones(c Cursor) error {
+	zones := make([]string, 0, len(info.Zones))
+	for k := range info.Zones {
+		zones = append(zones, k)
+	}
+	sort.Strings(zones)
+
+	var recurs []*Recur
+	var tasks []Task
+	for _, k := range zones {
+		z := info.Zones[k]
+		for _, t := range infoTypes {
+			recur := NewRecurType(z.Zone(), t)
+			recur.StartWith = z
+			recurs = append(recurs, recur)
+			tasks = append(tasks, recur)
 		}
+	}
 
+	_, e := c.Ts(tasks...)
+	for _, recur := range recurs {
 		info.appendAll(recur.Answers)
 	}
-	return nil
+	return e
+}
+
+// queryCaa looks for the CAA records from the domain up to the
+// registered domain, and the first set found is the one in effect.
+func (info *Info) queryCaa(c Cursor) {
+	reg := info.Domain.Registered()
+	if reg == nil {
+		return
+	}
+
+	for d := info.Domain; reg.IsZoneOf(d); d = d.Parent() {
+		recur := NewRecurType(d, CAA)
+		if _, e := c.T(recur); e != nil {
+			return
+		}
+
+		for _, rr := range recur.Answers {
+			if rr.Type == CAA && rr.Domain.Equal(d) {
+				info.Caas = append(info.Caas, rr)
+			}
+		}
+		if len(info.Caas) > 0 {
+			info.appendAll(info.Caas)
+			return
+		}
+	}
 }
 
 // PrintTo prints the info out via the printer.
@@ -186,7 +232,7 @@ func (info *Info) PrintTo(p *Printer) {
 
 		for _, r := range info.Results {
 			d := r.Domain
-			ip := RdToIPv4(r.Rdata)
+			ip := RdToIP(r.Rdata)
 			if d.Equal(info.Domain) {
 				p.Printf("%v", ip)
 			} else {
@@ -266,7 +312,7 @@ func (info *Info) Result() string {
 
 	for _, r := range info.Results {
 		d := r.Domain
-		ip := RdToIPv4(r.Rdata)
+		ip := RdToIP(r.Rdata)
 		var s string
 		if d.Equal(info.Domain) {
 			s = fmt.Sprintf("%v", ip)
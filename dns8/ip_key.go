@@ -0,0 +1,19 @@
+package dns8
+
+import (
+	"net"
+)
+
+// ipKey is a map key for both IPv4 and IPv6 addresses
+type ipKey [net.IPv6len]byte
+
+func keyOfIP(ip net.IP) ipKey {
+	bytes := []byte(ip.To16())
+	if bytes == nil {
+		panic("not an ip")
+	}
+
+	var ret ipKey
+	copy(ret[:], bytes)
+	return ret
+}
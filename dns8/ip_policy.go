@@ -0,0 +1,71 @@
+package dns8
+
+import (
+	"net"
+)
+
+// IP version policies for reaching name servers
+const (
+	IPv4Only = iota
+	IPv6Only
+	IPDual
+)
+
+func ipAllowed(policy int, ip net.IP) bool {
+	switch policy {
+	case IPv4Only:
+		return ip.To4() != nil
+	case IPv6Only:
+		return ip.To4() == nil
+	case IPDual:
+		return true
+	default:
+		panic("unknown ip policy")
+	}
+}
+
+// addrTypes returns the record types to resolve for name server
+// addresses, in the order of preference.
+func addrTypes(policy int) []uint16 {
+	switch policy {
+	case IPv4Only:
+		return []uint16{A}
+	case IPv6Only:
+		return []uint16{AAAA}
+	case IPDual:
+		return []uint16{A, AAAA}
+	default:
+		panic("unknown ip policy")
+	}
+}
+
+// policyServers filters the resolved servers with the policy. Servers
+// that have no allowed address are moved to the unresolved list, so that
+// they can be resolved for the other address type.
+func policyServers(policy int, res, unres []*NameServer) (
+	retRes, retUnres []*NameServer,
+) {
+	retRes = make([]*NameServer, 0, len(res))
+	reachable := make(map[string]bool)
+	for _, s := range res {
+		if ipAllowed(policy, s.IP) {
+			retRes = append(retRes, s)
+			reachable[s.Domain.key()] = true
+		}
+	}
+
+	retUnres = unres
+	for _, s := range res {
+		name := s.Domain.key()
+		if reachable[name] {
+			continue
+		}
+		reachable[name] = true // only add once
+		retUnres = append(retUnres, &NameServer{
+			Zone:   s.Zone,
+			Domain: s.Domain,
+		})
+	}
+
+	return retRes, retUnres
+}
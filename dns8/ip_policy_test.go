@@ -0,0 +1,135 @@
+package dns8
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestPolicyServers(t *testing.T) {
+	ns := func(d, ip string) *NameServer {
+		return &NameServer{D("example.com"), D(d), net.ParseIP(ip)}
+	}
+	str := func(ss []*NameServer) string {
+		var ret []string
+		for _, s := range ss {
+			if s.IP == nil {
+				ret = append(ret, s.Domain.String())
+			} else {
+				ret = append(ret, fmt.Sprintf("%v(%v)", s.Domain, s.IP))
+			}
+		}
+		return strings.Join(ret, " ")
+	}
+	res := []*NameServer{
+		ns("ns1.example.com", "10.0.1.1"),
+		ns("ns1.example.com", "2001:db8::1"),
+		ns("ns2.example.com", "2001:db8::2"),
+		ns("ns3.example.com", "10.0.1.3"),
+	}
+	unres := []*NameServer{{Zone: D("example.com"), Domain: D("ns4.com")}}
+
+	for _, test := range []struct {
+		policy int
+		types  []uint16
+		res    string
+		unres  string
+	}{
+		{IPv4Only, []uint16{A},
+			"ns1.example.com(10.0.1.1) ns3.example.com(10.0.1.3)",
+			"ns4.com ns2.example.com"},
+		{IPv6Only, []uint16{AAAA},
+			"ns1.example.com(2001:db8::1) ns2.example.com(2001:db8::2)",
+			"ns4.com ns3.example.com"},
+		{IPDual, []uint16{A, AAAA},
+			"ns1.example.com(10.0.1.1) ns1.example.com(2001:db8::1) " +
+				"ns2.example.com(2001:db8::2) ns3.example.com(10.0.1.3)",
+			"ns4.com"},
+	} {
+		if types := addrTypes(test.policy); fmt.Sprint(types) !=
+			fmt.Sprint(test.types) {
+			t.Errorf("policy %d: expect types %v, got %v",
+				test.policy, test.types, types)
+		}
+
+		gotRes, gotUnres := policyServers(test.policy, res,
+			append([]*NameServer(nil), unres...))
+		if s := str(gotRes); s != test.res {
+			t.Errorf("policy %d: expect resolved %s, got %s",
+				test.policy, test.res, s)
+		}
+		if s := str(gotUnres); s != test.unres {
+			t.Errorf("policy %d: expect unresolved %s, got %s",
+				test.policy, test.unres, s)
+		}
+	}
+}
+
+// dualInternet builds a tiny internet where the name servers have
+// both IPv4 and IPv6 addresses.
+func dualInternet() *MemNet {
+	n := NewMemNet()
+
+	root := &fakeServer{Root, []*RR{
+		rrNS("com", "a.gtld.com"),
+		rrA("a.gtld.com", "10.0.0.1"),
+		rrAAAA("a.gtld.com", "2001:db8::1"),
+	}}
+	for _, s := range MakeRoots().List() {
+		n.Handle(s.IP, root.handle)
+	}
+
+	ns := []*RR{
+		rrNS("example.com", "ns1.example.com"),
+		rrA("ns1.example.com", "10.0.1.1"),
+		rrAAAA("ns1.example.com", "2001:db8:1::1"),
+	}
+	com := &fakeServer{D("com"), ns}
+	n.Handle(net.ParseIP("10.0.0.1"), com.handle)
+	n.Handle(net.ParseIP("2001:db8::1"), com.handle)
+
+	example := &fakeServer{D("example.com"), append([]*RR{
+		rrSOA("example.com", 300),
+		rrA("v4.example.com", "10.0.2.4"),
+		rrAAAA("v6.example.com", "2001:db8::6"),
+		rrA("both.example.com", "10.0.2.5"),
+		rrAAAA("both.example.com", "2001:db8::5"),
+	}, ns...)}
+	n.Handle(net.ParseIP("10.0.1.1"), example.handle)
+	n.Handle(net.ParseIP("2001:db8:1::1"), example.handle)
+
+	return n
+}
+
+func TestResolveAddrs(t *testing.T) {
+	c := dualInternet().NewClient()
+	defer c.Close()
+
+	for _, test := range []struct {
+		policy int
+		d      string
+		ips    string
+	}{
+		{IPv4Only, "v4.example.com", "[10.0.2.4]"},
+		{IPv4Only, "v6.example.com", "[]"},
+		{IPv4Only, "both.example.com", "[10.0.2.5]"},
+		{IPv6Only, "v4.example.com", "[]"},
+		{IPv6Only, "v6.example.com", "[2001:db8::6]"},
+		{IPv6Only, "both.example.com", "[2001:db8::5]"},
+		{IPDual, "v4.example.com", "[10.0.2.4]"},
+		{IPDual, "v6.example.com", "[2001:db8::6]"},
+		{IPDual, "both.example.com", "[10.0.2.5 2001:db8::5]"},
+	} {
+		cur := testCursor(c)
+		cur.IPPolicy = test.policy
+		ips, e := resolveAddrs(cur, D(test.d), nil)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if s := fmt.Sprint(ips); s != test.ips {
+			t.Errorf("policy %d, %s: expect %s, got %s",
+				test.policy, test.d, test.ips, s)
+		}
+	}
+}
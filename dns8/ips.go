@@ -13,6 +13,7 @@ type ipsResult struct {
 // a particular domain
 type IPs struct {
 	Domain     *Domain
+	Type       uint16 // A or AAAA
 	StartWith  *ZoneServers
 	HeadLess   bool
 	HideResult bool
@@ -34,12 +35,18 @@ type IPs struct {
 	resultSave *ipsResult
 }
 
-// NewIPs creates a new query task for IPs.
+// NewIPs creates a new query task for IPv4 addresses.
 func NewIPs(d *Domain) *IPs {
-	return &IPs{Domain: d}
+	return NewIPsType(d, A)
 }
 
-// collectResults look for Query error or A records in Answer
+// NewIPsType creates a new query task for IP addresses,
+// where t is A or AAAA.
+func NewIPsType(d *Domain, t uint16) *IPs {
+	return &IPs{Domain: d, Type: t}
+}
+
+// collectResults look for Query error or address records in Answer
 func (ips *IPs) collectResults(recur *Recur) {
 	if recur.Return != Okay {
 		panic("bug")
@@ -47,7 +54,7 @@ func (ips *IPs) collectResults(recur *Recur) {
 
 	for _, rr := range recur.Answers {
 		switch rr.Type {
-		case A:
+		case ips.Type:
 			ips.Records = append(ips.Records, rr)
 		case CNAME:
 			// okay
@@ -61,7 +68,7 @@ func (ips *IPs) findCnameResults(recur *Recur) (unresolved []*Domain) {
 	unresolved = make([]*Domain, 0, len(ips.CnameEndpoints))
 
 	for _, cname := range ips.CnameEndpoints {
-		rrs := recur.Packet.SelectRecords(cname, A)
+		rrs := recur.Packet.SelectRecords(cname, ips.Type)
 		if len(rrs) == 0 {
 			unresolved = append(unresolved, cname)
 			continue
@@ -74,7 +81,7 @@ func (ips *IPs) findCnameResults(recur *Recur) (unresolved []*Domain) {
 
 // Returns true when if finds any endpoints
 func (ips *IPs) extractCnames(recur *Recur, d *Domain, c Cursor) bool {
-	if _, found := ips.CnameTraceBack[d.String()]; !found {
+	if _, found := ips.CnameTraceBack[d.key()]; !found {
 		panic("bug")
 	}
 
@@ -90,7 +97,7 @@ func (ips *IPs) extractCnames(recur *Recur, d *Domain, c Cursor) bool {
 
 	for _, rr := range rrs {
 		cname := RdToDomain(rr.Rdata)
-		cnameStr := cname.String()
+		cnameStr := cname.key()
 		if ips.CnameTraceBack[cnameStr] != nil {
 			// some error cnames, pointing to self or forming circles
 			continue
@@ -98,7 +105,7 @@ func (ips *IPs) extractCnames(recur *Recur, d *Domain, c Cursor) bool {
 
 		c.P().Printf("// cname: %v -> %v", d, cname)
 		ips.CnameRecords = append(ips.CnameRecords, rr)
-		ips.CnameTraceBack[cname.String()] = d
+		ips.CnameTraceBack[cname.key()] = d
 
 		// see if it follows another CNAME
 		if ips.extractCnames(recur, cname, c) {
@@ -130,7 +137,7 @@ func (ips *IPs) PrintResult(c Cursor) {
 	}
 
 	for _, r := range results {
-		p.Printf("// %v(%v)", r.Domain, RdToIPv4(r.Rdata))
+		p.Printf("// %v(%v)", r.Domain, RdToIP(r.Rdata))
 	}
 }
 
@@ -175,12 +182,12 @@ func (ips *IPs) ResultAndIPs() (cnames, res []*RR, retIPs []net.IP) {
 		return
 	}
 
-	hits := make(map[uint32]bool)
+	hits := make(map[ipKey]bool)
 	retIPs = make([]net.IP, 0, len(res))
 
 	for _, rr := range res {
-		ip := RdToIPv4(rr.Rdata)
-		index := ipUint(ip)
+		ip := RdToIP(rr.Rdata)
+		index := keyOfIP(ip)
 		if hits[index] {
 			continue
 		}
@@ -209,7 +216,7 @@ func (ips *IPs) results(cnames, results []*RR) (c, r []*RR) {
 }
 
 func (ips *IPs) run(c Cursor) {
-	recur := NewRecur(ips.Domain)
+	recur := NewRecurType(ips.Domain, ips.Type)
 	recur.HeadLess = true
 	recur.StartWith = ips.StartWith
 
@@ -235,9 +242,9 @@ func (ips *IPs) run(c Cursor) {
 	ips.CnameEndpoints = make([]*Domain, 0, 10)
 	if ips.CnameTraceBack == nil {
 		ips.CnameTraceBack = make(map[string]*Domain)
-		ips.CnameTraceBack[ips.Domain.String()] = nil
+		ips.CnameTraceBack[ips.Domain.key()] = nil
 	} else {
-		_, found := ips.CnameTraceBack[ips.Domain.String()]
+		_, found := ips.CnameTraceBack[ips.Domain.key()]
 		if !found {
 			panic("bug")
 		}
@@ -261,6 +268,7 @@ func (ips *IPs) run(c Cursor) {
 	p := ips.Packet
 	z := ips.EndWith
 	ips.CnameIPs = make(map[string]*IPs)
+	tasks := make([]Task, 0, len(unresolved))
 
 	for _, cname := range unresolved {
 		// search for redirects
@@ -279,18 +287,30 @@ func (ips *IPs) run(c Cursor) {
 			}
 		}
 
-		cnameIPs := NewIPs(cname)
+		cnameIPs := NewIPsType(cname, ips.Type)
 		cnameIPs.HideResult = true
 		cnameIPs.StartWith = servers
-		cnameIPs.CnameTraceBack = ips.CnameTraceBack
+		cnameIPs.CnameTraceBack = ips.traceBack(len(unresolved) > 1)
 
-		ips.CnameIPs[cname.String()] = cnameIPs
+		ips.CnameIPs[cname.key()] = cnameIPs
+		tasks = append(tasks, cnameIPs)
+	}
 
-		_, e := c.T(cnameIPs)
-		if e != nil {
-			return
-		}
+	c.Ts(tasks...)
+}
+
+// traceBack returns the cname trace back map for a sub IPs. Concurrent
+// siblings each trace back on a copy, so they do not race.
+func (ips *IPs) traceBack(sibling bool) map[string]*Domain {
+	if !sibling {
+		return ips.CnameTraceBack
+	}
+
+	ret := make(map[string]*Domain, len(ips.CnameTraceBack))
+	for k, v := range ips.CnameTraceBack {
+		ret[k] = v
 	}
+	return ret
 }
 
 // PrintTo prints the task via the printer
@@ -310,7 +330,7 @@ func (ips *IPs) PrintTo(p *Printer) {
 
 		for _, r := range results {
 			d := r.Domain
-			ip := RdToIPv4(r.Rdata)
+			ip := RdToIP(r.Rdata)
 			if d.Equal(ips.Domain) {
 				p.Printf("%v", ip)
 			} else {
@@ -320,18 +340,33 @@ func (ips *IPs) PrintTo(p *Printer) {
 	}
 }
 
-func init() {
-	nsResolve = func(c Cursor, d *Domain, zs *ZoneServers) ([]net.IP, error) {
-		t := NewIPs(d)
+// resolveAddrs resolves the addresses of a host following the ip
+// policy, and adds the records into zs when it is not nil. With dual
+// stack, both A and AAAA are queried.
+func resolveAddrs(c Cursor, d *Domain, zs *ZoneServers) ([]net.IP, error) {
+	var ret []net.IP
+	for _, typ := range addrTypes(c.Config().IPPolicy) {
+		t := NewIPsType(d, typ)
 		if _, e := c.T(t); e != nil {
 			return nil, e
 		}
 
 		cnames, res, ips := t.ResultAndIPs()
-		zs.AddRecords(cnames)
-		zs.AddRecords(res)
-		zs.Add(d, ips...)
+		if zs != nil {
+			zs.AddRecords(cnames)
+			zs.AddRecords(res)
+			zs.Add(d, ips...)
+		}
+
+		ret = append(ret, ips...)
+	}
+
+	return ret, nil
+}
 
-		return ips, nil
+func init() {
+	nsResolve = resolveAddrs
+	targetResolve = func(c Cursor, d *Domain) ([]net.IP, error) {
+		return resolveAddrs(c, d, nil)
 	}
 }
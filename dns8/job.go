@@ -1,6 +1,8 @@
 package dns8
 
 import (
+	"context"
+	"fmt"
 	"time"
 )
 
@@ -9,15 +11,38 @@ type job struct {
 	id       uint16
 	exchange *Exchange
 	deadline time.Time
-	printer  *Printer
-	c        chan<- *Exchange
+	flight   *flight
+	flights  *flights
+
+	caseMismatch bool // got a reply that only differs in letter cases
+
+	stopTCP context.CancelFunc // aborts the tcp retry in flight
 }
 
-func (j *job) Close() {
-	if j.printer != nil {
-		j.exchange.printRecv(j.printer)
+// match checks if the message is a reply for the job, that it has
+// the same id and question, and comes from the server queried.
+func (j *job) match(m *Message) error {
+	send := j.exchange.Send
+	from := m.RemoteAddr
+	to := send.RemoteAddr
+
+	if m.Packet.ID != send.Packet.ID {
+		return fmt.Errorf("id mismatch: %d, expect %d",
+			m.Packet.ID, send.Packet.ID)
+	}
+	if !from.IP.Equal(to.IP) || from.Port != to.Port {
+		return fmt.Errorf("reply from %v, expect %v", from, to)
 	}
-	j.c <- j.exchange
+	if !m.Packet.Question.Equal(send.Packet.Question) {
+		return fmt.Errorf("question mismatch: %v, expect %v",
+			m.Packet.Question, send.Packet.Question)
+	}
+
+	return nil
+}
+
+func (j *job) Close() {
+	j.flights.land(j.flight, j.exchange)
 }
 
 func (j *job) CloseErr(e error) {
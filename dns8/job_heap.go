@@ -0,0 +1,27 @@
+package dns8
+
+// jobHeap is a min-heap of jobs ordered by deadline.
+// It implements heap.Interface.
+type jobHeap []*job
+
+func (h jobHeap) Len() int           { return len(h) }
+func (h jobHeap) Less(i, j int) bool { return h[i].deadline.Before(h[j].deadline) }
+func (h jobHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *jobHeap) Push(x interface{}) { *h = append(*h, x.(*job)) }
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	ret := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return ret
+}
+
+func (h jobHeap) top() *job {
+	if len(h) == 0 {
+		return nil
+	}
+	return h[0]
+}
@@ -3,21 +3,44 @@ package dns8
 // Leaf is a leaf node in a query tree
 type Leaf struct {
 	Attempts []*Exchange
+	Ends     []int // how each attempt ended
 }
 
 var _ Node = new(Leaf)
 
+// How an attempt ended
+const (
+	EndReply   = iota // got a usable reply
+	EndTimeout        // no reply before the deadline
+	EndError          // failed to send or receive
+	EndRcode          // replied with a server failure rcode
+)
+
+var endStrings = []string{
+	EndReply:   "reply",
+	EndTimeout: "timeout",
+	EndError:   "error",
+	EndRcode:   "server failure",
+}
+
+// EndString returns the string of how an attempt ended.
+func EndString(end int) string {
+	return endStrings[end]
+}
+
 // IsLeaf returns true.
 func (lf *Leaf) IsLeaf() bool { return true }
 
 func newLeaf(retry int) *Leaf {
 	ret := new(Leaf)
 	ret.Attempts = make([]*Exchange, 0, retry)
+	ret.Ends = make([]int, 0, retry)
 	return ret
 }
 
-func (lf *Leaf) add(e *Exchange) {
+func (lf *Leaf) add(e *Exchange, end int) {
 	lf.Attempts = append(lf.Attempts, e)
+	lf.Ends = append(lf.Ends, end)
 }
 
 // Last returns the last attempt
@@ -28,3 +51,12 @@ func (lf *Leaf) Last() *Exchange {
 	}
 	return lf.Attempts[n-1]
 }
+
+// LastEnd returns how the last attempt ended.
+func (lf *Leaf) LastEnd() int {
+	n := len(lf.Ends)
+	if n == 0 {
+		return EndError
+	}
+	return lf.Ends[n-1]
+}
@@ -0,0 +1,210 @@
+package dns8
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// MemHandler handles a query for a fake name server. It returns nil
+// to drop the query.
+type MemHandler func(q *Packet) *Packet
+
+// MemNet is an in-memory network with fake name servers, where the
+// servers are registered by IP. It is for testing tasks without
+// reaching the real network.
+type MemNet struct {
+	lock     sync.Mutex
+	handlers map[ipKey]MemHandler
+	caseless map[ipKey]bool
+}
+
+// NewMemNet creates an empty in-memory network.
+func NewMemNet() *MemNet {
+	ret := new(MemNet)
+	ret.handlers = make(map[ipKey]MemHandler)
+	ret.caseless = make(map[ipKey]bool)
+	return ret
+}
+
+// Handle registers a fake name server at ip. A nil handler
+// unregisters the server.
+func (n *MemNet) Handle(ip net.IP, h MemHandler) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if h == nil {
+		delete(n.handlers, keyOfIP(ip))
+	} else {
+		n.handlers[keyOfIP(ip)] = h
+	}
+}
+
+// Caseless makes the server at ip reply with lower case question
+// names, like the servers that do not preserve the letter cases.
+// Other servers echo the question names as queried.
+func (n *MemNet) Caseless(ip net.IP) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.caseless[keyOfIP(ip)] = true
+}
+
+func (n *MemNet) handler(ip net.IP) (MemHandler, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	k := keyOfIP(ip)
+	return n.handlers[k], n.caseless[k]
+}
+
+// Transport creates a new transport end on the network for a client.
+func (n *MemNet) Transport() Transport {
+	return &memTransport{
+		net:     n,
+		recvs:   make(chan *datagram, 100),
+		closing: make(chan struct{}),
+	}
+}
+
+// NewClient creates a new client on the network.
+func (n *MemNet) NewClient() *Client {
+	return NewClientTransport(n.Transport())
+}
+
+// Reply creates an empty response packet for a query.
+func Reply(q *Packet) *Packet {
+	return &Packet{
+		ID:       q.ID,
+		Flag:     FlagResponse | q.Flag&(OpMask|FlagRD),
+		Question: q.Question,
+	}
+}
+
+// maxUDPSize returns the largest reply that fits in a datagram
+// for query q.
+func maxUDPSize(q *Packet) int {
+	if edns := q.Edns(); edns != nil && edns.UDPSize > 512 {
+		return int(edns.UDPSize)
+	}
+	return 512
+}
+
+func truncated(p *Packet) *Packet {
+	return &Packet{
+		ID:       p.ID,
+		Flag:     p.Flag | FlagTC,
+		Question: p.Question,
+	}
+}
+
+type memTransport struct {
+	net     *MemNet
+	recvs   chan *datagram
+	closing chan struct{}
+	once    sync.Once
+}
+
+var _ Transport = new(memTransport)
+
+var errNoServer = errors.New("no server")
+
+// serve runs the handler and returns the reply bytes, nil if the
+// query is dropped.
+func (t *memTransport) serve(p []byte, addr *net.UDPAddr, tcp bool) []byte {
+	h, caseless := t.net.handler(addr.IP)
+	if h == nil {
+		return nil
+	}
+
+	q, e := Unpack(p)
+	if e != nil {
+		return nil
+	}
+	if caseless && q.Question != nil {
+		q.Question.Domain = q.Question.Domain.canonical()
+	}
+
+	reply := h(q)
+	if reply == nil {
+		return nil
+	}
+
+	ret := reply.Pack()
+	if !tcp && len(ret) > maxUDPSize(q) {
+		ret = truncated(reply).Pack()
+	}
+	if !caseless && reply.Question != nil && reply.Question.Equal(q.Question) {
+		echoCase(ret, p)
+	}
+	return ret
+}
+
+// echoCase copies the question name of query q into reply p, which
+// only differs in the letter cases.
+func echoCase(p, q []byte) {
+	n := qnameLen(q)
+	if n < 0 || n != qnameLen(p) {
+		return
+	}
+	copy(p[questionOffset:questionOffset+n], q[questionOffset:])
+}
+
+func (t *memTransport) Send(p []byte, addr *net.UDPAddr) error {
+	select {
+	case <-t.closing:
+		return ErrClosed
+	default:
+	}
+
+	go func() {
+		reply := t.serve(p, addr, false)
+		if reply == nil {
+			return
+		}
+
+		d := &datagram{p: reply, addr: addr}
+		select {
+		case t.recvs <- d:
+		case <-t.closing:
+		}
+	}()
+
+	return nil
+}
+
+func (t *memTransport) Recv() ([]byte, *net.UDPAddr, error) {
+	select {
+	case d := <-t.recvs:
+		return d.p, d.addr, nil
+	case <-t.closing:
+		return nil, nil, ErrClosed
+	}
+}
+
+func (t *memTransport) ExchangeTCP(ctx context.Context, p []byte,
+	addr *net.UDPAddr,
+) ([]byte, error) {
+	if h, _ := t.net.handler(addr.IP); h == nil {
+		return nil, errNoServer
+	}
+
+	replies := make(chan []byte, 1)
+	go func() { replies <- t.serve(p, addr, true) }()
+
+	select {
+	case reply := <-replies:
+		if reply == nil {
+			return nil, errTimeout
+		}
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *memTransport) Close() error {
+	t.once.Do(func() { close(t.closing) })
+	return nil
+}
@@ -0,0 +1,760 @@
+package dns8
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is an authoritative name server for a set of records.
+type fakeServer struct {
+	zone    *Domain
+	records []*RR
+}
+
+func rrA(d string, ip string) *RR {
+	return &RR{D(d), A, IN, 3600, RdIPv4(net.ParseIP(ip))}
+}
+
+func rrAAAA(d string, ip string) *RR {
+	return &RR{D(d), AAAA, IN, 3600, RdIPv6(net.ParseIP(ip))}
+}
+
+func rrNS(d string, ns string) *RR {
+	return &RR{D(d), NS, IN, 3600, (*RdDomain)(D(ns))}
+}
+
+func rrCNAME(d string, cname string) *RR {
+	return &RR{D(d), CNAME, IN, 3600, (*RdDomain)(D(cname))}
+}
+
+func rrSOA(zone string, min uint32) *RR {
+	soa := &RdSoa{
+		Mname:   strings.Split("ns1."+zone, "."),
+		Rname:   strings.Split("admin."+zone, "."),
+		Minimum: min,
+	}
+	return &RR{D(zone), SOA, IN, 3600, soa}
+}
+
+func (s *fakeServer) find(d *Domain, t uint16) []*RR {
+	var ret []*RR
+	for _, rr := range s.records {
+		if rr.Domain.Equal(d) && rr.Type == t {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+// glue finds the addresses of a name server.
+func (s *fakeServer) glue(ns *Domain) []*RR {
+	return append(s.find(ns, A), s.find(ns, AAAA)...)
+}
+
+func (s *fakeServer) handle(q *Packet) *Packet {
+	ret := Reply(q)
+	d := q.Question.Domain
+	t := q.Question.Type
+
+	// delegations, closest to the zone first
+	for cut := d; !cut.Equal(s.zone); cut = cut.Parent() {
+		if cut.IsRoot() {
+			return nil // not our zone
+		}
+		if (t == NS || t == DS) && cut.Equal(d) {
+			continue // answered by the parent
+		}
+		nss := s.find(cut, NS)
+		if len(nss) == 0 {
+			continue
+		}
+
+		ret.Authority = nss
+		for _, ns := range nss {
+			ret.Addition = append(ret.Addition,
+				s.glue(RdToDomain(ns.Rdata))...)
+		}
+		return ret
+	}
+
+	ret.Flag |= FlagAA
+	if ans := s.find(d, t); len(ans) > 0 {
+		ret.Answer = append(ans, s.sigs(d, t)...)
+		if t == NS {
+			for _, ns := range ans {
+				ret.Addition = append(ret.Addition,
+					s.glue(RdToDomain(ns.Rdata))...)
+			}
+		}
+		return ret
+	} else if ans := s.find(d, CNAME); len(ans) > 0 {
+		ret.Answer = append(ans, s.sigs(d, CNAME)...)
+		return ret
+	}
+
+	if !s.exists(d) {
+		ret.Flag |= RcodeNameError
+	}
+	ret.Authority = append(s.find(s.zone, SOA), s.sigs(s.zone, SOA)...)
+
+	// all the denial records, for the tiny zones
+	for _, rr := range s.records {
+		if rr.Type == NSEC || rr.Type == NSEC3 {
+			ret.Authority = append(ret.Authority, rr)
+			ret.Authority = append(ret.Authority,
+				s.sigs(rr.Domain, rr.Type)...)
+		}
+	}
+	return ret
+}
+
+// sigs finds the signatures over the records of a domain and a type.
+func (s *fakeServer) sigs(d *Domain, t uint16) []*RR {
+	var ret []*RR
+	for _, rr := range s.find(d, RRSIG) {
+		if rr.Rdata.(*RdRrsig).TypeCovered == t {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+func (s *fakeServer) exists(d *Domain) bool {
+	for _, rr := range s.records {
+		// names with records under them are empty non-terminals
+		if rr.Domain.Equal(d) || d.IsParentOf(rr.Domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeInternet builds a tiny internet with a root, a com zone and
+// an example.com zone.
+func fakeInternet() *MemNet {
+	n := NewMemNet()
+
+	root := &fakeServer{Root, []*RR{
+		rrNS("com", "a.gtld.com"),
+		rrA("a.gtld.com", "10.0.0.1"),
+	}}
+	for _, s := range MakeRoots().List() {
+		n.Handle(s.IP, root.handle)
+	}
+
+	com := &fakeServer{D("com"), []*RR{
+		rrNS("example.com", "ns1.example.com"),
+		rrNS("example.com", "ns2.example.com"),
+		rrA("ns1.example.com", "10.0.1.1"),
+		rrA("ns2.example.com", "10.0.1.2"),
+	}}
+	n.Handle(net.ParseIP("10.0.0.1"), com.handle)
+
+	example := &fakeServer{D("example.com"), []*RR{
+		rrNS("example.com", "ns1.example.com"),
+		rrNS("example.com", "ns2.example.com"),
+		rrA("ns1.example.com", "10.0.1.1"),
+		rrA("ns2.example.com", "10.0.1.2"),
+		rrA("example.com", "10.0.2.1"),
+		rrCNAME("www.example.com", "example.com"),
+		rrSOA("example.com", 300),
+		{D("_sip._tcp.example.com"), SRV, IN, 3600,
+			&RdSrv{10, 5, 5060, D("sip.example.com")}},
+		rrA("sip.example.com", "10.0.2.2"),
+		{D("example.com"), HTTPS, IN, 3600, &RdSvcb{1, Root, nil}},
+		{D("svc.example.com"), HTTPS, IN, 3600,
+			&RdSvcb{0, D("example.com"), nil}},
+		{D("loop.example.com"), HTTPS, IN, 3600,
+			&RdSvcb{0, D("loop.example.com"), nil}},
+		{D("_xmpp._tcp.example.com"), SRV, IN, 3600,
+			&RdSrv{10, 5, 5222, D("sip.example.com")}},
+		{D("_xmpp._tcp.example.com"), SRV, IN, 3600,
+			&RdSrv{20, 5, 5222, D("example.com")}},
+		{D("example.com"), CAA, IN, 3600,
+			&RdCaa{0, "issue", []byte("ca.example.net")}},
+		{D("example.com"), TXT, IN, 3600,
+			RdTxt(strings.Repeat("v=spf1 include:_spf.example.com ", 30))},
+	}}
+	n.Handle(net.ParseIP("10.0.1.1"), example.handle)
+	n.Handle(net.ParseIP("10.0.1.2"), example.handle)
+
+	return n
+}
+
+func testCursor(c *Client) *cursor {
+	cfg := &TermConfig{
+		PrintFlag: PrintReply,
+		Retry:     DefaultRetryPolicy(),
+	}
+	return newCursor(cfg, c)
+}
+
+func TestMemNetIPs(t *testing.T) {
+	c := fakeInternet().NewClient()
+	defer c.Close()
+
+	ips := NewIPs(D("www.example.com"))
+	if _, e := testCursor(c).T(ips); e != nil {
+		t.Fatal(e)
+	}
+
+	got := ips.IPs()
+	if len(got) != 1 || !got[0].Equal(net.ParseIP("10.0.2.1")) {
+		t.Errorf("www.example.com, expect 10.0.2.1, got %v", got)
+	}
+
+	cnames, _ := ips.Results()
+	if len(cnames) != 1 {
+		t.Errorf("expect one cname, got %d", len(cnames))
+	}
+}
+
+func TestMemNetNotExists(t *testing.T) {
+	c := fakeInternet().NewClient()
+	defer c.Close()
+
+	r := NewRecur(D("none.example.com"))
+	if _, e := testCursor(c).T(r); e != nil {
+		t.Fatal(e)
+	}
+	if r.Return != NotExists {
+		t.Errorf("expect not exists, got %d", r.Return)
+	}
+}
+
+func TestMemNetTCP(t *testing.T) {
+	c := fakeInternet().NewClient()
+	defer c.Close()
+
+	r := NewRecurType(D("example.com"), TXT)
+	cur := testCursor(c)
+	b, e := cur.T(r)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if r.Return != Okay || len(r.Answers) != 1 {
+		t.Fatalf("expect one txt answer, got %v", r.Answers)
+	}
+
+	last := b.Children[len(b.Children)-1].(*Leaf).Last()
+	if !last.TCP {
+		t.Error("expect the exchange to be retried over tcp")
+	}
+}
+
+func TestMemNetDeadServer(t *testing.T) {
+	n := fakeInternet()
+	dead := net.ParseIP("10.0.1.1")
+	n.Handle(dead, nil)
+
+	c := n.NewClient()
+	defer c.Close()
+	c.MaxTimeout = time.Millisecond * 50
+
+	timeouts := func() int {
+		stat, _ := c.RTT(dead)
+		return stat.Timeouts
+	}
+
+	resolve := func() {
+		ips := NewIPs(D("example.com"))
+		cur := testCursor(c)
+		cur.ServerOrder = NewFastOrder(c)
+		if _, e := cur.T(ips); e != nil {
+			t.Fatal(e)
+		}
+		if len(ips.IPs()) != 1 {
+			t.Fatalf("expect one ip, got %v", ips.IPs())
+		}
+	}
+
+	for i := 0; i < 20 && timeouts() == 0; i++ {
+		resolve()
+	}
+	if timeouts() != 1 {
+		t.Fatalf("expect one timeout, got %d", timeouts())
+	}
+	if c.Health.Healthy(dead) {
+		t.Error("dead server should be unhealthy")
+	}
+
+	for i := 0; i < 5; i++ {
+		resolve()
+	}
+	if timeouts() != 1 {
+		t.Errorf("unhealthy server is queried again")
+	}
+}
+
+func TestMemNetSpoofed(t *testing.T) {
+	n := fakeInternet()
+	liar := net.ParseIP("10.0.1.1")
+	n.Handle(liar, func(q *Packet) *Packet {
+		ret := Reply(q)
+		ret.Question = &Question{D("evil.com"), A, IN}
+		ret.Answer = Section{rrA("evil.com", "6.6.6.6")}
+		return ret
+	})
+
+	c := n.NewClient()
+	defer c.Close()
+	c.MaxTimeout = time.Millisecond * 50
+
+	cur := testCursor(c)
+	leaf, e := cur.Q(Q(D("example.com"), A, liar))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !leaf.Last().Timeout() {
+		t.Error("spoofed reply should be dropped")
+	}
+	if c.Stats().Mismatches == 0 {
+		t.Error("spoofed reply should be counted")
+	}
+}
+
+// spoofTransport injects a forged reply in the flipped letter cases
+// before each genuine reply, which is delayed.
+type spoofTransport struct {
+	Transport
+	recvs chan *datagram
+}
+
+func newSpoofTransport(t Transport) *spoofTransport {
+	ret := &spoofTransport{t, make(chan *datagram, 10)}
+	go func() {
+		for {
+			p, addr, e := t.Recv()
+			if e != nil {
+				close(ret.recvs)
+				return
+			}
+			ret.recvs <- &datagram{p: p, addr: addr}
+		}
+	}()
+	return ret
+}
+
+func (t *spoofTransport) Send(p []byte, addr *net.UDPAddr) error {
+	q, e := Unpack(p)
+	if e != nil {
+		return e
+	}
+	forged := Reply(q)
+	forged.Answer = Section{rrA(q.Question.Domain.String(), "6.6.6.6")}
+	bs := forged.Pack()
+	copy(bs[questionOffset:], p[questionOffset:questionOffset+qnameLen(p)])
+	bs[questionOffset+1] ^= 0x20 // the first letter
+	t.recvs <- &datagram{p: bs, addr: addr}
+
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		t.Transport.Send(p, addr)
+	}()
+	return nil
+}
+
+func (t *spoofTransport) Recv() ([]byte, *net.UDPAddr, error) {
+	d, ok := <-t.recvs
+	if !ok {
+		return nil, nil, ErrClosed
+	}
+	return d.p, d.addr, nil
+}
+
+func Test0x20Spoofed(t *testing.T) {
+	n := fakeInternet()
+	c := NewClientTransport(newSpoofTransport(n.Transport()))
+	defer c.Close()
+	c.Use0x20 = true
+	c.MaxTimeout = time.Second
+
+	cur := testCursor(c)
+	leaf, e := cur.Q(Q(D("example.com"), A, net.ParseIP("10.0.1.1")))
+	if e != nil {
+		t.Fatal(e)
+	}
+	last := leaf.Last()
+	if len(leaf.Ends) != 1 || !last.Mixed || last.Recv == nil {
+		t.Fatalf("expect the genuine reply, got %v", leaf.Ends)
+	}
+	ips := last.Recv.Packet.SelectIPs(D("example.com"))
+	if len(ips) != 1 || !RdToIP(ips[0].Rdata).Equal(net.ParseIP("10.0.2.1")) {
+		t.Errorf("expect 10.0.2.1, got %v", ips)
+	}
+
+	if c.Stats().CaseMismatches != 1 {
+		t.Errorf("expect one case mismatch, got %d", c.Stats().CaseMismatches)
+	}
+	if quirks := c.CaseQuirks(); len(quirks) != 0 {
+		t.Errorf("expect no server recorded, got %v", quirks)
+	}
+}
+
+func Test0x20(t *testing.T) {
+	n := fakeInternet()
+	quirk := net.ParseIP("10.0.1.1")
+	n.Caseless(quirk)
+
+	c := n.NewClient()
+	defer c.Close()
+	c.Use0x20 = true
+
+	cur := testCursor(c)
+	leaf, e := cur.Q(Q(D("example.com"), A, net.ParseIP("10.0.1.2")))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if last := leaf.Last(); !last.Mixed || last.Recv == nil {
+		t.Error("expect a reply to the mixed case query")
+	}
+
+	c.MaxTimeout = time.Millisecond * 50
+	leaf, e = cur.Q(Q(D("example.com"), A, quirk))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(leaf.Ends) != 2 || leaf.Ends[0] != EndError {
+		t.Fatalf("expect a case mismatch and a retry, got %v", leaf.Ends)
+	}
+	if last := leaf.Last(); last.Mixed || last.Recv == nil {
+		t.Error("expect the retry to fall back to the plain name")
+	}
+
+	if c.Stats().CaseMismatches != 1 {
+		t.Errorf("expect one case mismatch, got %d", c.Stats().CaseMismatches)
+	}
+	quirks := c.CaseQuirks()
+	if len(quirks) != 1 || !quirks[0].Equal(quirk) {
+		t.Errorf("expect %v recorded, got %v", quirk, quirks)
+	}
+}
+
+func TestMemNetCancel(t *testing.T) {
+	n := fakeInternet()
+	n.Handle(net.ParseIP("10.0.1.1"), nil)
+	n.Handle(net.ParseIP("10.0.1.2"), nil)
+
+	c := n.NewClient()
+	defer c.Close()
+	c.MinTimeout = time.Hour
+	c.MaxTimeout = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		time.Millisecond*50)
+	defer cancel()
+
+	tm := NewTerm(c)
+	_, e := tm.TContext(ctx, NewIPs(D("example.com")))
+	if e != ErrCanceled {
+		t.Fatalf("expect canceled, got %v", e)
+	}
+
+	if stats := c.Stats(); stats.InFlight != 0 {
+		t.Errorf("expect no query in flight, got %d", stats.InFlight)
+	}
+}
+
+// hangTCP is a transport whose tcp exchanges hang until canceled.
+type hangTCP struct {
+	Transport
+}
+
+func (t hangTCP) ExchangeTCP(ctx context.Context, p []byte,
+	addr *net.UDPAddr,
+) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestCancelTCP(t *testing.T) {
+	c := NewClientTransport(hangTCP{fakeInternet().Transport()})
+	defer c.Close()
+	c.MaxTimeout = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		time.Millisecond*50)
+	defer cancel()
+
+	out := new(bytes.Buffer)
+	q := &QueryPrinter{
+		Query:   Q(D("example.com"), TXT, net.ParseIP("10.0.1.1")),
+		Printer: NewPrinter(out),
+	}
+	start := time.Now()
+	x := c.QueryContext(ctx, q)
+	if x.Error != ErrCanceled || !x.TCP {
+		t.Fatalf("expect a canceled tcp retry, got %v", x.Error)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("canceled after %v", d)
+	}
+	if !strings.Contains(out.String(), ErrCanceled.Error()) {
+		t.Errorf("expect the canceled exchange printed, got %q", out)
+	}
+	if stats := c.Stats(); stats.InFlight != 0 {
+		t.Errorf("expect no query in flight, got %d", stats.InFlight)
+	}
+}
+
+func TestCancelBackoff(t *testing.T) {
+	n := fakeInternet()
+	n.Handle(net.ParseIP("10.0.1.1"), nil)
+	n.Handle(net.ParseIP("10.0.1.2"), nil)
+
+	c := n.NewClient()
+	defer c.Close()
+	c.MinTimeout = time.Millisecond * 10
+	c.MaxTimeout = time.Millisecond * 10
+
+	tm := NewTerm(c)
+	tm.Log = nil
+	tm.Retry.Backoff = time.Hour
+	tm.Retry.MaxBackoff = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		time.Millisecond*100)
+	defer cancel()
+
+	start := time.Now()
+	_, e := tm.TContext(ctx, NewRecur(D("example.com")))
+	if e != ErrCanceled {
+		t.Fatalf("expect canceled, got %v", e)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("backoff does not stop on cancel")
+	}
+
+	tm.Timeout = time.Millisecond * 100
+	start = time.Now()
+	if _, e := tm.T(NewRecur(D("example.com"))); e != ErrTimeBudget {
+		t.Fatalf("expect time budget exceeded, got %v", e)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("backoff runs over the time budget")
+	}
+}
+
+func TestBudget(t *testing.T) {
+	n := fakeInternet()
+	c := n.NewClient()
+	defer c.Close()
+
+	tm := NewTerm(c)
+	tm.MaxQuery = 2
+	b, e := tm.T(NewIPs(D("www.example.com")))
+	if e != ErrTooManyQueries {
+		t.Fatalf("expect too many queries, got %v", e)
+	}
+	if b == nil || len(b.Children) == 0 {
+		t.Error("expect the partial tree")
+	}
+
+	n.Handle(net.ParseIP("10.0.1.1"), nil)
+	n.Handle(net.ParseIP("10.0.1.2"), nil)
+	c.MinTimeout = time.Hour
+	c.MaxTimeout = time.Hour
+
+	tm.MaxQuery = 0
+	tm.Timeout = time.Millisecond * 50
+	start := time.Now()
+	_, e = tm.T(NewIPs(D("example.com")))
+	if e != ErrTimeBudget || !IsBudgetError(e) {
+		t.Fatalf("expect time budget exceeded, got %v", e)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("task runs over the time budget")
+	}
+}
+
+func TestInfoSiblings(t *testing.T) {
+	c := fakeInternet().NewClient()
+	defer c.Close()
+
+	log := new(bytes.Buffer)
+	cur := testCursor(c)
+	cur.Printer = NewPrinter(log)
+
+	info := NewInfo(D("www.example.com"))
+	b, e := cur.T(info)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var got []string
+	for _, child := range b.Children {
+		if r, ok := child.(*Branch).Task.(*Recur); ok {
+			got = append(got, TypeString(r.Type))
+		}
+	}
+	if strings.Join(got, " ") != "ns mx soa txt" {
+		t.Errorf("expect ns mx soa txt, got %v", got)
+	}
+
+	last := -1
+	for _, typ := range got {
+		i := strings.Index(log.String(), "recur example.com "+typ+" {")
+		if i < last {
+			t.Errorf("%s is printed out of order", typ)
+		}
+		last = i
+	}
+
+	if len(info.Records) == 0 {
+		t.Error("expect records of the zone")
+	}
+}
+
+func TestMemNetCoalesce(t *testing.T) {
+	n := fakeInternet()
+	slow := net.ParseIP("10.0.1.1")
+	var lock sync.Mutex
+	hits := 0
+	n.Handle(slow, func(q *Packet) *Packet {
+		lock.Lock()
+		hits++
+		lock.Unlock()
+
+		time.Sleep(time.Millisecond * 50)
+		ret := Reply(q)
+		ret.Answer = Section{rrA("example.com", "10.0.2.1")}
+		return ret
+	})
+
+	c := n.NewClient()
+	defer c.Close()
+
+	const N = 5
+	var wg sync.WaitGroup
+	xs := make([]*Exchange, N)
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			qp := &QueryPrinter{Query: Q(D("example.com"), A, slow)}
+			xs[i] = c.Query(qp)
+		}(i)
+	}
+	wg.Wait()
+
+	if hits != 1 {
+		t.Errorf("expect one query sent, got %d", hits)
+	}
+	if c.Stats().Coalesced != N-1 {
+		t.Errorf("expect %d coalesced, got %d", N-1, c.Stats().Coalesced)
+	}
+	for i, x := range xs {
+		if x.Recv == nil || len(x.Recv.Packet.Answer) != 1 {
+			t.Errorf("exchange %d: expect the reply, got %v", i, x.Error)
+		}
+		for j := 0; j < i; j++ {
+			if xs[j] == x {
+				t.Errorf("exchange %d and %d are the same", i, j)
+			}
+		}
+	}
+}
+
+func TestMemNetPrintSend(t *testing.T) {
+	n := fakeInternet()
+	slow := net.ParseIP("10.0.1.1")
+	release := make(chan struct{})
+	n.Handle(slow, func(q *Packet) *Packet {
+		<-release
+		ret := Reply(q)
+		ret.Answer = Section{rrA("example.com", "10.0.2.1")}
+		return ret
+	})
+
+	c := n.NewClient()
+	defer c.Close()
+
+	outs := make([]*bytes.Buffer, 2)
+	chs := make([]chan *Exchange, 2)
+	for i := range outs {
+		outs[i] = new(bytes.Buffer)
+		chs[i] = make(chan *Exchange, 1)
+		c.Send(&QueryPrinter{
+			Query:   Q(D("example.com"), A, slow),
+			Printer: NewPrinter(outs[i]),
+		}, chs[i])
+	}
+
+	// the send is printed before the reply lands
+	for i, out := range outs {
+		if s := out.String(); !strings.Contains(s, "send {") ||
+			strings.Contains(s, "recv {") {
+			t.Errorf("query %d: expect only the send printed, got %q", i, s)
+		}
+	}
+
+	close(release)
+	for i, ch := range chs {
+		<-ch
+		if strings.Count(outs[i].String(), "send {") != 1 ||
+			!strings.Contains(outs[i].String(), "recv {") {
+			t.Errorf("query %d: expect the send and the recv, got %q",
+				i, outs[i])
+		}
+	}
+	if !strings.Contains(outs[1].String(), "// coalesced") {
+		t.Errorf("expect the second query coalesced, got %q", outs[1])
+	}
+}
+
+func TestRRCache(t *testing.T) {
+	c := fakeInternet().NewClient()
+	defer c.Close()
+
+	cache := NewRRCache()
+	resolve := func(task Task) *Branch {
+		cur := testCursor(c)
+		cur.RRCache = cache
+		b, e := cur.T(task)
+		if e != nil {
+			t.Fatal(e)
+		}
+		return b
+	}
+
+	resolve(NewIPs(D("www.example.com")))
+	sent := c.Stats().Sent
+	ips := NewIPs(D("www.example.com"))
+	b := resolve(ips)
+	if c.Stats().Sent != sent {
+		t.Errorf("expect no query sent, got %d", c.Stats().Sent-sent)
+	}
+	if got := ips.IPs(); len(got) != 1 {
+		t.Errorf("expect one ip from the cache, got %v", got)
+	}
+	leaf := b.Children[0].(*Branch).Children[0].(*Leaf)
+	if !leaf.Last().Cached {
+		t.Error("expect a cache hit in the tree")
+	}
+
+	// the delegation of example.com is reused
+	resolve(NewRecurType(D("example.com"), MX))
+	if n := c.Stats().Sent - sent; n != 1 {
+		t.Errorf("expect one query for the mx, got %d", n)
+	}
+
+	// negative answers are cached
+	sent = c.Stats().Sent
+	r := NewRecurType(D("example.com"), MX)
+	resolve(r)
+	if r.Return != NotExists || c.Stats().Sent != sent {
+		t.Error("expect the empty answer cached")
+	}
+	resolve(NewRecur(D("none.example.com")))
+	sent = c.Stats().Sent
+	r = NewRecurType(D("none.example.com"), TXT)
+	resolve(r)
+	if r.Return != NotExists || c.Stats().Sent != sent {
+		t.Error("expect the name error cached")
+	}
+}
@@ -0,0 +1,124 @@
+package dns8
+
+import (
+	"bytes"
+	"crypto/rand"
+	"net"
+	"sync"
+)
+
+// questionOffset is where the question starts in a packet
+const questionOffset = 12
+
+// qnameLen returns the length of the raw question name in packet p,
+// -1 if the name is invalid.
+func qnameLen(p []byte) int {
+	i := questionOffset
+	for i < len(p) {
+		n := int(p[i])
+		if n == 0 {
+			return i + 1 - questionOffset
+		}
+		if n > 63 {
+			return -1 // question names are never compressed
+		}
+		i += n + 1
+	}
+	return -1
+}
+
+// mixCase randomizes the letter cases of the question name in packet p
+// in place, as in draft-vixie-dnsext-dns0x20.
+func mixCase(p []byte) {
+	n := qnameLen(p)
+	if n < 0 {
+		return
+	}
+
+	bits := make([]byte, (n+7)/8)
+	if _, e := rand.Read(bits); e != nil {
+		panic(e)
+	}
+
+	name := p[questionOffset : questionOffset+n]
+	for i, c := range name {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			continue
+		}
+		if bits[i/8]&(1<<uint(i%8)) != 0 {
+			name[i] = c ^ 0x20 // flips the case
+		}
+	}
+}
+
+// sameCase checks if the question names in the two packets are
+// exactly the same, including the letter cases.
+func sameCase(p1, p2 []byte) bool {
+	n := qnameLen(p1)
+	if n < 0 || n != qnameLen(p2) {
+		return false
+	}
+
+	end := questionOffset + n
+	return bytes.Equal(p1[questionOffset:end], p2[questionOffset:end])
+}
+
+// caseTable records the servers that do not preserve the letter
+// cases of question names. A server is first suspected when a mixed
+// case query gets only replies in other cases, and is recorded as a
+// quirk when a plain query to it then succeeds.
+type caseTable struct {
+	lock     sync.Mutex
+	quirks   map[ipKey]bool
+	suspects map[ipKey]bool
+}
+
+func newCaseTable() *caseTable {
+	ret := new(caseTable)
+	ret.quirks = make(map[ipKey]bool)
+	ret.suspects = make(map[ipKey]bool)
+	return ret
+}
+
+// suspect suspects a server, which is queried in plain names to
+// verify it.
+func (t *caseTable) suspect(ip net.IP) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.suspects[keyOfIP(ip)] = true
+}
+
+// verify records the result of a plain query to a server. When the
+// server is suspected, a reply confirms it as a quirk, and a failure
+// clears the suspicion.
+func (t *caseTable) verify(ip net.IP, replied bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	k := keyOfIP(ip)
+	if !t.suspects[k] {
+		return
+	}
+	delete(t.suspects, k)
+	if replied {
+		t.quirks[k] = true
+	}
+}
+
+func (t *caseTable) preserves(ip net.IP) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	k := keyOfIP(ip)
+	return !t.quirks[k] && !t.suspects[k]
+}
+
+func (t *caseTable) list() []net.IP {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	ret := make([]net.IP, 0, len(t.quirks))
+	for k := range t.quirks {
+		ret = append(ret, net.IP(append([]byte(nil), k[:]...)))
+	}
+	return ret
+}
@@ -8,7 +8,7 @@ import (
 func PackRdata(out *bytes.Buffer, rdata Rdata) {
 	pack := rdata.Pack()
 	n := len(pack)
-	if n > 255 {
+	if n > 0xffff {
 		panic("rdata too long")
 	}
 
@@ -2,8 +2,8 @@ package dns8
 
 import (
 	"bytes"
+	"crypto/rand"
 	"errors"
-	"math/rand"
 )
 
 // Packet is an DNS packet
@@ -18,12 +18,34 @@ type Packet struct {
 	Addition  Section
 }
 
-// Rcode returns the rcode of the packet
+// Rcode returns the rcode of the packet. If the packet has an OPT
+// record, the extended rcode bits are included.
 func (p *Packet) Rcode() uint16 {
-	return p.Flag & RcodeMask
+	ret := p.Flag & RcodeMask
+	if edns := p.Edns(); edns != nil {
+		ret |= uint16(edns.ExtRcode) << 4
+	}
+	return ret
+}
+
+// Edns returns the EDNS0 setting in the additional section,
+// nil if the packet has no OPT record.
+func (p *Packet) Edns() *Edns {
+	for _, rr := range p.Addition {
+		if rr.Type == OPT {
+			return RRToEdns(rr)
+		}
+	}
+	return nil
 }
 
-func randomID() uint16 { return uint16(rand.Uint32()) }
+func randomID() uint16 {
+	var buf [2]byte
+	if _, e := rand.Read(buf[:]); e != nil {
+		panic(e)
+	}
+	return enc.Uint16(buf[:])
+}
 
 // Unpack unpacks a packet
 func Unpack(p []byte) (*Packet, error) {
@@ -52,8 +74,10 @@ func (p *Packet) unpack() error {
 	}
 
 	if p.Flag&FlagTC != 0 {
+		// the sections might be cut short, but the OPT record is
+		// kept for the extended rcode and the options
+		p.Addition = p.truncatedOpt(in)
 		p.Authority = p.Authority[0:0]
-		p.Addition = p.Addition[0:0]
 		return nil
 	}
 
@@ -66,6 +90,22 @@ func (p *Packet) unpack() error {
 	return nil
 }
 
+// truncatedOpt parses the rest of a truncated packet, best effort,
+// and returns the OPT record in the additional section if found.
+func (p *Packet) truncatedOpt(in *bytes.Reader) Section {
+	if p.Authority.unpack(in, p.Bytes) != nil {
+		return p.Addition[0:0]
+	}
+
+	p.Addition.unpack(in, p.Bytes) // keeps the records before an error
+	for _, rr := range p.Addition {
+		if rr != nil && rr.Type == OPT {
+			return Section{rr}
+		}
+	}
+	return p.Addition[0:0]
+}
+
 func (p *Packet) unpackHeader(in *bytes.Reader) error {
 	buf := make([]byte, 12)
 	if _, e := in.Read(buf); e != nil {
@@ -98,12 +138,20 @@ func (p *Packet) packHeader(out *bytes.Buffer) {
 	out.Write(buf)
 }
 
-// PackQuery packs a query.
+// PackQuery packs a query, with all the sections it has.
 func (p *Packet) PackQuery() []byte {
+	return p.Pack()
+}
+
+// Pack packs the packet with all its sections into Bytes.
+func (p *Packet) Pack() []byte {
 	out := new(bytes.Buffer)
 
 	p.packHeader(out)
 	p.Question.pack(out)
+	p.Answer.pack(out)
+	p.Authority.pack(out)
+	p.Addition.pack(out)
 
 	p.Bytes = out.Bytes() // swap in
 	return p.Bytes
@@ -116,6 +164,12 @@ func Qpack(d *Domain, t uint16) *Packet {
 
 // QpackID makes a query pakcet with a particular id
 func QpackID(d *Domain, t, id uint16) *Packet {
+	return QpackEdns(d, t, id, nil)
+}
+
+// QpackEdns makes a query packet with a particular id. If edns is not
+// nil, an OPT record is added in the additional section.
+func QpackEdns(d *Domain, t, id uint16, edns *Edns) *Packet {
 	m := new(Packet)
 
 	if t == 0 {
@@ -125,6 +179,9 @@ func QpackID(d *Domain, t, id uint16) *Packet {
 	m.ID = id
 	m.Flag = 0
 	m.Question = &Question{d, t, IN}
+	if edns != nil {
+		m.Addition = Section{edns.RR()}
+	}
 	m.PackQuery()
 
 	return m
@@ -158,6 +215,11 @@ func (p *Packet) SelectIPs(d *Domain) []*RR {
 	return p.SelectWith(&SelectIP{d})
 }
 
+// SelectAddrs selects A and AAAA records for a domain.
+func (p *Packet) SelectAddrs(d *Domain) []*RR {
+	return p.SelectWith(&SelectAddr{d})
+}
+
 // SelectRedirects selects redirection related records
 func (p *Packet) SelectRedirects(z, d *Domain) []*RR {
 	return p.SelectWith(&SelectRedirect{z, d})
@@ -168,6 +230,17 @@ func (p *Packet) SelectAnswers(d *Domain, t uint16) []*RR {
 	return p.SelectWith(&SelectAnswer{d, t})
 }
 
+// SelectSigs selects the RRSIG records over the records of a
+// particular type and domain.
+func (p *Packet) SelectSigs(d *Domain, t uint16) []*RR {
+	return p.SelectWith(&SelectSig{d, t})
+}
+
+// SelectDenials selects the NSEC and NSEC3 records of a zone.
+func (p *Packet) SelectDenials(z *Domain) []*RR {
+	return p.SelectWith(&SelectDenial{z})
+}
+
 // SelectRecords select records for of a particular type and
 // domain
 func (p *Packet) SelectRecords(d *Domain, t uint16) []*RR {
@@ -0,0 +1,89 @@
+package dns8
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+var errPrime = errors.New("priming failed")
+
+// primedRoots makes the root name server set from a priming reply,
+// nil if the reply has no root server with an address.
+func primedRoots(p *Packet) *ZoneServers {
+	ret := NewZoneServers(Root)
+	found := false
+	for _, rr := range p.SelectRecords(Root, NS) {
+		server := RdToDomain(rr.Rdata)
+		glue := p.SelectAddrs(server)
+		ret.AddRecords(glue)
+
+		ips := make([]net.IP, 0, len(glue))
+		for _, addr := range glue {
+			ips = append(ips, RdToIP(addr.Rdata))
+		}
+		ret.Add(server, ips...)
+		found = found || len(ips) > 0
+	}
+
+	if !found {
+		return nil
+	}
+	return ret
+}
+
+// prime queries the hinted root servers one by one for the root name
+// servers, until a valid answer.
+func prime(c *cursor, hints *ZoneServers) (*ZoneServers, error) {
+	c.Print("prime {")
+	c.ShiftIn()
+	defer c.ShiftOut("}")
+
+	resolved, _ := hints.Prepare()
+	resolved, _ = policyServers(c.IPPolicy, resolved, nil)
+	for _, server := range resolved {
+		q := &Query{
+			Domain:     Root,
+			Type:       NS,
+			Server:     Server(server.IP),
+			Zone:       Root,
+			ServerName: server.Domain,
+		}
+		leaf, e := c.Q(q)
+		if e != nil {
+			return nil, e
+		}
+		if leaf.LastEnd() != EndReply {
+			continue
+		}
+
+		if ret := primedRoots(leaf.Last().Recv.Packet); ret != nil {
+			return ret, nil
+		}
+		c.Printf("// no root server address from %v", server.Domain)
+	}
+
+	c.Print("// use the hints")
+	return nil, errPrime
+}
+
+// Prime queries the root servers in the hints for the current root
+// name servers, and uses them as the roots of the term. On failure,
+// the term keeps using the hints.
+func (tm *Term) Prime() error {
+	return tm.PrimeContext(context.Background())
+}
+
+// PrimeContext is Prime with a context.
+func (tm *Term) PrimeContext(ctx context.Context) error {
+	cur := newCursorContext(ctx, tm.TermConfig, tm.client)
+	defer cur.close()
+
+	ret, e := prime(cur, tm.roots())
+	if e != nil {
+		return e
+	}
+
+	tm.Roots = ret
+	return nil
+}
@@ -14,6 +14,9 @@ type Query struct {
 
 	Zone       *Domain
 	ServerName *Domain
+
+	Edns    *Edns // EDNS0 setting, nil for a plain query
+	Use0x20 bool  // randomizes the letter cases of the query name
 }
 
 // Server converts an IP address to UDP address with DNSPort
@@ -61,7 +64,7 @@ func (q *Query) String() string {
 func newMessage(q *Query, id uint16) *Message {
 	return &Message{
 		RemoteAddr: q.Server,
-		Packet:     QpackID(q.Domain, q.Type, id),
+		Packet:     QpackEdns(q.Domain, q.Type, id, q.Edns),
 		Timestamp:  time.Now(),
 	}
 }
@@ -46,6 +46,11 @@ func (q *Question) unpackFlags(in *bytes.Reader) error {
 	return nil
 }
 
+// Equal checks if the question is the same as o.
+func (q *Question) Equal(o *Question) bool {
+	return q.Domain.Equal(o.Domain) && q.Type == o.Type && q.Class == o.Class
+}
+
 func (q *Question) String() string {
 	ret := fmt.Sprintf("%s %s", q.Domain.String(), TypeString(q.Type))
 	if q.Class != IN {
@@ -0,0 +1,153 @@
+package dns8
+
+import (
+	"container/list"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a token bucket that allows reservations. A reservation
+// might take the tokens into debt, and the caller waits for the debt to
+// be paid off, so that the requests are queued in order.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func bucketBurst(rate float64) float64 { return math.Max(1, rate) }
+
+func newTokenBucket(rate float64, now time.Time) *tokenBucket {
+	return &tokenBucket{bucketBurst(rate), now}
+}
+
+func (b *tokenBucket) refill(rate float64, now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * rate
+		b.last = now
+	}
+	b.tokens = math.Min(b.tokens, bucketBurst(rate))
+}
+
+// reserve takes a token and returns the time to wait before using it.
+func (b *tokenBucket) reserve(rate float64, now time.Time) time.Duration {
+	b.refill(rate, now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / rate * float64(time.Second))
+}
+
+func (b *tokenBucket) full(rate float64, now time.Time) bool {
+	b.refill(rate, now)
+	return b.tokens >= bucketBurst(rate)
+}
+
+// maxServerBuckets is the number of per-server buckets to keep. Only
+// the buckets that are refilled are evicted, the least recently used
+// first, so the map grows over the limit when all are in use.
+const maxServerBuckets = 4096
+
+type serverBucket struct {
+	key ipKey
+	*tokenBucket
+}
+
+// rateLimiter limits the query rate globally and for each server ip.
+type rateLimiter struct {
+	lock    sync.Mutex
+	global  *tokenBucket
+	servers map[ipKey]*list.Element
+	lru     *list.List // of *serverBucket, most recently used first
+}
+
+func newRateLimiter() *rateLimiter {
+	ret := new(rateLimiter)
+	ret.servers = make(map[ipKey]*list.Element)
+	ret.lru = list.New()
+	return ret
+}
+
+// evict removes the refilled buckets, least recently used first,
+// until there is room for a new one. A bucket in debt is kept, or the
+// server would get a full burst again.
+func (l *rateLimiter) evict(rate float64, now time.Time) {
+	for elem := l.lru.Back(); elem != nil; {
+		if l.lru.Len() < maxServerBuckets {
+			return
+		}
+		prev := elem.Prev()
+		if b := elem.Value.(*serverBucket); b.full(rate, now) {
+			l.lru.Remove(elem)
+			delete(l.servers, b.key)
+		}
+		elem = prev
+	}
+}
+
+// server returns the bucket of a server ip, and evicts the idle
+// buckets when there are too many.
+func (l *rateLimiter) server(ip net.IP, rate float64, now time.Time) *tokenBucket {
+	k := keyOfIP(ip)
+	if elem := l.servers[k]; elem != nil {
+		l.lru.MoveToFront(elem)
+		return elem.Value.(*serverBucket).tokenBucket
+	}
+
+	if l.lru.Len() >= maxServerBuckets {
+		l.evict(rate, now)
+	}
+	b := &serverBucket{k, newTokenBucket(rate, now)}
+	l.servers[k] = l.lru.PushFront(b)
+	return b.tokenBucket
+}
+
+// reservation is the tokens taken for a query.
+type reservation struct {
+	wait    time.Duration
+	buckets []*tokenBucket
+}
+
+// reserve reserves a query to ip and returns the time to wait.
+// A rate that is not positive means no limit.
+func (l *rateLimiter) reserve(ip net.IP, rate, serverRate float64) *reservation {
+	ret := new(reservation)
+	if rate <= 0 && serverRate <= 0 {
+		return ret
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if rate > 0 {
+		if l.global == nil {
+			l.global = newTokenBucket(rate, now)
+		}
+		ret.wait = l.global.reserve(rate, now)
+		ret.buckets = append(ret.buckets, l.global)
+	}
+
+	if serverRate > 0 {
+		b := l.server(ip, serverRate, now)
+		if wait := b.reserve(serverRate, now); wait > ret.wait {
+			ret.wait = wait
+		}
+		ret.buckets = append(ret.buckets, b)
+	}
+
+	return ret
+}
+
+// refund gives back the tokens of a reservation that is not used, like
+// when the query is canceled while waiting.
+func (l *rateLimiter) refund(r *reservation) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, b := range r.buckets {
+		b.tokens++
+	}
+}
@@ -0,0 +1,160 @@
+package dns8
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(10, now)
+
+	for i, test := range []struct {
+		after time.Duration // since the start
+		wait  time.Duration
+	}{
+		{0, 0},
+		{0, 0},
+		{time.Second, 0}, // refilled, but only up to the burst
+	} {
+		if wait := b.reserve(10, now.Add(test.after)); wait != test.wait {
+			t.Errorf("reserve %d: expect %v, got %v", i, test.wait, wait)
+		}
+	}
+
+	for i := 0; i < 9; i++ {
+		b.reserve(10, now.Add(time.Second))
+	}
+	for i, expect := range []time.Duration{
+		100 * time.Millisecond, // in debt, queued in order
+		200 * time.Millisecond,
+	} {
+		if wait := b.reserve(10, now.Add(time.Second)); wait != expect {
+			t.Errorf("debt %d: expect %v, got %v", i, expect, wait)
+		}
+	}
+}
+
+func TestRateLimiterRefund(t *testing.T) {
+	l := newRateLimiter()
+	ip := net.ParseIP("10.0.0.1")
+
+	l.reserve(ip, 1, 1)
+	r := l.reserve(ip, 1, 1)
+	if r.wait < 900*time.Millisecond {
+		t.Fatalf("expect waiting for a second, got %v", r.wait)
+	}
+
+	l.refund(r)
+	if wait := l.reserve(ip, 1, 1).wait; wait > r.wait {
+		t.Errorf("expect no more than %v after the refund, got %v",
+			r.wait, wait)
+	}
+}
+
+func TestRateLimiterEvict(t *testing.T) {
+	ip := func(i int) net.IP {
+		return net.IPv4(10, byte(i>>16), byte(i>>8), byte(i))
+	}
+	has := func(l *rateLimiter, i int) bool {
+		return l.servers[keyOfIP(ip(i))] != nil
+	}
+
+	// the first bucket is in debt for a second, and the others are
+	// refilled in a millisecond
+	const rate = 1000
+	l := newRateLimiter()
+	for i := 0; i < 2*rate+1; i++ {
+		l.reserve(ip(0), 0, rate)
+	}
+	for i := 1; i < maxServerBuckets; i++ {
+		l.reserve(ip(i), 0, rate)
+	}
+	time.Sleep(10 * time.Millisecond)
+	for i := maxServerBuckets; i < maxServerBuckets+10; i++ {
+		l.reserve(ip(i), 0, rate)
+	}
+
+	if n := len(l.servers); n != maxServerBuckets {
+		t.Errorf("expect %d buckets, got %d", maxServerBuckets, n)
+	}
+	if !has(l, 0) {
+		t.Error("expect the bucket in debt kept")
+	}
+	if has(l, 1) {
+		t.Error("expect the least recently used refilled bucket evicted")
+	}
+	if !has(l, maxServerBuckets+9) {
+		t.Error("expect the last bucket kept")
+	}
+
+	// when all the buckets are in debt, none is evicted
+	l = newRateLimiter()
+	for i := 0; i < maxServerBuckets+10; i++ {
+		l.reserve(ip(i), 0, 0.001)
+		l.reserve(ip(i), 0, 0.001)
+	}
+	if n := len(l.servers); n != maxServerBuckets+10 {
+		t.Errorf("expect all %d buckets kept, got %d",
+			maxServerBuckets+10, n)
+	}
+}
+
+func TestRateLimitQueued(t *testing.T) {
+	c := fakeInternet().NewClient()
+	defer c.Close()
+	c.RateLimit = 20
+
+	// the sends block while queued, one after another
+	const n = 24
+	server := net.ParseIP("10.0.1.1")
+	chs := make([]chan *Exchange, n)
+	start := time.Now()
+	for i := range chs {
+		d := D(fmt.Sprintf("n%d.example.com", i))
+		chs[i] = make(chan *Exchange, 1)
+		c.Send(&QueryPrinter{Query: Q(d, A, server)}, chs[i])
+	}
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Errorf("expect 4 queries queued for 200ms, sent in %v", d)
+	}
+	for i, ch := range chs {
+		x := <-ch
+		switch {
+		case i < 20 && x.Queued != 0:
+			t.Errorf("query %d: expect no wait in the burst, got %v",
+				i, x.Queued)
+		case x.Queued > 60*time.Millisecond:
+			// the queue has one at a time, refilled every 50ms
+			t.Errorf("query %d: expect queued up to 50ms, got %v",
+				i, x.Queued)
+		}
+	}
+
+	// a query canceled in the queue is not sent, and gives back its
+	// token to the next one
+	var before time.Duration
+	for i := 0; i < 4; i++ {
+		before = c.limiter.reserve(server, c.RateLimit, 0).wait
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	q := &QueryPrinter{Query: Q(D("canceled.example.com"), A, server)}
+	if x := c.QueryContext(ctx, q); x.Error != ErrCanceled {
+		t.Fatalf("expect canceled, got %v", x.Error)
+	}
+
+	// 50ms more for its own token, and another 50ms without the refund
+	after := c.limiter.reserve(server, c.RateLimit, 0).wait
+	if after > before+75*time.Millisecond {
+		t.Errorf("expect queued for %v at most, got %v",
+			before+50*time.Millisecond, after)
+	}
+	if sent := c.Stats().Sent; sent != n {
+		t.Errorf("expect %d queries sent, got %d", n, sent)
+	}
+}
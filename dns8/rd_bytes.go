@@ -2,10 +2,13 @@ package dns8
 
 import (
 	"bytes"
+	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
-// RdBytes is just an array of bytes
+// RdBytes is just an array of bytes, the rdata of unknown types.
 type RdBytes []byte
 
 var _ Rdata = RdBytes(nil)
@@ -20,21 +23,43 @@ func (bs RdBytes) Pack() []byte {
 // UnpackRdBytes unpacks the rdata as bytes.
 func UnpackRdBytes(in *bytes.Reader, n uint16) (RdBytes, error) {
 	ret := make([]byte, n)
-	if _, e := in.Read([]byte(ret)); e != nil {
+	if _, e := in.Read([]byte(ret)); e != nil && n > 0 {
 		return nil, e
 	}
 
 	return RdBytes(ret), nil
 }
 
-// PrintTo prints it out
+// PrintTo prints it out in the generic format of RFC 3597, like
+// \# 4 0a000001.
 func (bs RdBytes) PrintTo(out *bytes.Buffer) {
-	fmt.Fprintf(out, "[")
-	for i, b := range bs {
-		if i > 0 && i%4 == 0 {
-			fmt.Fprintf(out, " ")
-		}
-		fmt.Fprintf(out, "%02x", b)
+	fmt.Fprintf(out, "\\# %d", len(bs))
+	if len(bs) > 0 {
+		fmt.Fprintf(out, " %x", []byte(bs))
 	}
-	fmt.Fprintf(out, "]")
+}
+
+// ParseRdBytes parses an rdata in the generic format of RFC 3597,
+// where the hex digits might be split by spaces.
+func ParseRdBytes(s string) (RdBytes, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 || fields[0] != "\\#" {
+		return nil, fmt.Errorf("invalid generic rdata %q", s)
+	}
+
+	n, e := strconv.ParseUint(fields[1], 10, 16)
+	if e != nil {
+		return nil, fmt.Errorf("invalid generic rdata length %q", fields[1])
+	}
+
+	ret, e := hex.DecodeString(strings.Join(fields[2:], ""))
+	if e != nil {
+		return nil, e
+	}
+	if len(ret) != int(n) {
+		return nil, fmt.Errorf("generic rdata expect %d bytes, got %d",
+			n, len(ret))
+	}
+
+	return RdBytes(ret), nil
 }
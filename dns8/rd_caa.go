@@ -0,0 +1,58 @@
+package dns8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// CaaCritical is the issuer critical flag of a CAA record.
+const CaaCritical = 0x80
+
+// RdCaa is a CAA rdata, a certification authority authorization of
+// RFC 8659, like issue "letsencrypt.org".
+type RdCaa struct {
+	Flags uint8
+	Tag   string
+	Value []byte
+}
+
+// UnpackRdCaa unpacks a CAA record.
+func UnpackRdCaa(in *bytes.Reader, n uint16) (*RdCaa, error) {
+	if n < 2 {
+		return nil, fmt.Errorf("caa with %d bytes", n)
+	}
+
+	buf := make([]byte, n)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+
+	ntag := int(buf[1])
+	if ntag == 0 || 2+ntag > len(buf) {
+		return nil, fmt.Errorf("caa with tag of %d bytes", ntag)
+	}
+
+	return &RdCaa{
+		Flags: buf[0],
+		Tag:   string(buf[2 : 2+ntag]),
+		Value: buf[2+ntag:],
+	}, nil
+}
+
+// Critical checks if the issuer critical flag is set.
+func (d *RdCaa) Critical() bool { return d.Flags&CaaCritical != 0 }
+
+// PrintTo prints the record in the presentation format.
+func (d *RdCaa) PrintTo(out *bytes.Buffer) {
+	fmt.Fprintf(out, "%d %s ", d.Flags, d.Tag)
+	quoteCharString(out, d.Value)
+}
+
+// Pack packs the record.
+func (d *RdCaa) Pack() []byte {
+	ret := make([]byte, 2, 2+len(d.Tag)+len(d.Value))
+	ret[0] = d.Flags
+	ret[1] = byte(len(d.Tag))
+	ret = append(ret, d.Tag...)
+	return append(ret, d.Value...)
+}
@@ -0,0 +1,56 @@
+package dns8
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+)
+
+// DNSKEY flags
+const (
+	DnskeyZone   = 0x0100 // a zone key
+	DnskeyRevoke = 0x0080 // revoked, RFC 5011
+	DnskeySEP    = 0x0001 // secure entry point, usually a key signing key
+)
+
+// RdDnskey is a DNSKEY rdata, a public key of a zone.
+type RdDnskey struct {
+	Flags     uint16
+	Protocol  uint8 // always 3
+	Algorithm uint8
+	PublicKey []byte
+}
+
+// UnpackRdDnskey unpacks a DNSKEY record.
+func UnpackRdDnskey(in *bytes.Reader, n uint16) (*RdDnskey, error) {
+	if n <= 4 {
+		return nil, fmt.Errorf("dnskey with %d bytes", n)
+	}
+
+	buf := make([]byte, n)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+
+	return &RdDnskey{
+		Flags:     enc.Uint16(buf[0:2]),
+		Protocol:  buf[2],
+		Algorithm: buf[3],
+		PublicKey: buf[4:],
+	}, nil
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdDnskey) PrintTo(out *bytes.Buffer) {
+	fmt.Fprintf(out, "%d %d %d %s", d.Flags, d.Protocol, d.Algorithm,
+		base64.StdEncoding.EncodeToString(d.PublicKey))
+}
+
+// Pack packs the record.
+func (d *RdDnskey) Pack() []byte {
+	ret := make([]byte, 4, 4+len(d.PublicKey))
+	enc.PutUint16(ret[0:2], d.Flags)
+	ret[2] = d.Protocol
+	ret[3] = d.Algorithm
+	return append(ret, d.PublicKey...)
+}
@@ -0,0 +1,134 @@
+package dns8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDnssecRdata(t *testing.T) {
+	zone := D("example.com")
+	rrs := []*RR{
+		{zone, DS, IN, 3600, &RdDs{
+			KeyTag: 12345, Algorithm: 13, DigestType: 2,
+			Digest: []byte{0xde, 0xad, 0xbe, 0xef},
+		}},
+		{zone, DNSKEY, IN, 3600, &RdDnskey{
+			Flags: DnskeyZone | DnskeySEP, Protocol: 3, Algorithm: 13,
+			PublicKey: []byte("public key"),
+		}},
+		{zone, RRSIG, IN, 3600, &RdRrsig{
+			TypeCovered: A, Algorithm: 13, Labels: 2, OrigTTL: 3600,
+			Expiration: 1700000000, Inception: 1690000000,
+			KeyTag: 12345, SignerName: zone,
+			Signature: []byte("signature"),
+		}},
+		{zone, NSEC, IN, 3600, &RdNsec{
+			NextDomain: D("www.example.com"),
+			Types:      []uint16{A, NS, SOA, RRSIG, NSEC, DNSKEY, 257},
+		}},
+		{zone, NSEC3, IN, 3600, &RdNsec3{
+			HashAlgorithm: 1, Flags: Nsec3OptOut, Iterations: 10,
+			Salt:       []byte{0xab, 0xcd},
+			NextHashed: []byte("0123456789abcdefghij"),
+			Types:      []uint16{A, RRSIG},
+		}},
+		{zone, NSEC3PARAM, IN, 0, &RdNsec3Param{
+			HashAlgorithm: 1, Iterations: 10,
+		}},
+	}
+
+	p := &Packet{
+		Flag:      FlagResponse,
+		Question:  &Question{zone, A, IN},
+		Authority: rrs,
+	}
+	got, e := Unpack(p.Pack())
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	for i, rr := range got.Authority {
+		if rr.String() != rrs[i].String() {
+			t.Errorf("expect %q, got %q", rrs[i], rr)
+		}
+	}
+
+	expects := []string{
+		"example.com ds 12345 13 2 DEADBEEF 1h",
+		"example.com rrsig a 13 2 3600 20231114221320 20230722042640 " +
+			"12345 example.com c2lnbmF0dXJl 1h",
+		"example.com nsec www.example.com a ns soa rrsig nsec dnskey caa 1h",
+		"example.com nsec3param 1 0 10 - 0",
+	}
+	for i, j := range []int{0, 2, 3, 5} {
+		if s := got.Authority[j].String(); s != expects[i] {
+			t.Errorf("expect %q, got %q", expects[i], s)
+		}
+	}
+
+	if sigs := got.SelectSigs(zone, A); len(sigs) != 1 {
+		t.Errorf("expect one rrsig over a, got %d", len(sigs))
+	}
+	if sigs := got.SelectSigs(zone, MX); len(sigs) != 0 {
+		t.Errorf("expect no rrsig over mx, got %d", len(sigs))
+	}
+	if denials := got.SelectDenials(zone); len(denials) != 2 {
+		t.Errorf("expect two denial records, got %d", len(denials))
+	}
+	if !got.Authority[3].Rdata.(*RdNsec).HasType(257) {
+		t.Error("expect type 257 in the bitmap")
+	}
+}
+
+func TestDnssecRdataCase(t *testing.T) {
+	mixed := func(s string) *Domain {
+		return &Domain{s, strings.Split(s, ".")}
+	}
+
+	zone := D("example.com")
+	rrs := []*RR{
+		{mixed("Example.com"), RRSIG, IN, 3600, &RdRrsig{
+			TypeCovered: A, Algorithm: 13, Labels: 2, OrigTTL: 3600,
+			KeyTag: 12345, SignerName: mixed("Example.COM"),
+			Signature: []byte("signature"),
+		}},
+		{mixed("Example.com"), NSEC, IN, 3600, &RdNsec{
+			NextDomain: mixed("WWW.example.com"),
+			Types:      []uint16{A, RRSIG, NSEC},
+		}},
+	}
+
+	p := &Packet{
+		Flag:      FlagResponse,
+		Question:  &Question{mixed("eXample.com"), A, IN},
+		Authority: rrs,
+	}
+	got, e := Unpack(p.Pack())
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// the names keep the cases, RFC 6840 section 5.1
+	for i, rr := range got.Authority {
+		if rr.String() != rrs[i].String() {
+			t.Errorf("expect %q, got %q", rrs[i], rr)
+		}
+		if !bytes.Equal(rr.Rdata.Pack(), rrs[i].Rdata.Pack()) {
+			t.Errorf("rdata of %q changed after the round trip", rr)
+		}
+	}
+
+	// but are compared regardless of the cases
+	if !got.Question.Domain.Equal(zone) {
+		t.Errorf("expect %v equal to %v", got.Question.Domain, zone)
+	}
+	if sigs := got.SelectSigs(zone, A); len(sigs) != 1 {
+		t.Errorf("expect one rrsig over a, got %d", len(sigs))
+	}
+	next := got.Authority[1].Rdata.(*RdNsec).NextDomain
+	if canonicalCompare(next, D("www.example.com")) != 0 ||
+		!zone.IsParentOf(next) {
+		t.Errorf("expect %v as www.example.com", next)
+	}
+}
@@ -0,0 +1,48 @@
+package dns8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// RdDs is a DS rdata, the digest of a DNSKEY of the child zone.
+type RdDs struct {
+	KeyTag     uint16
+	Algorithm  uint8
+	DigestType uint8
+	Digest     []byte
+}
+
+// UnpackRdDs unpacks a DS record.
+func UnpackRdDs(in *bytes.Reader, n uint16) (*RdDs, error) {
+	if n <= 4 {
+		return nil, fmt.Errorf("ds with %d bytes", n)
+	}
+
+	buf := make([]byte, n)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+
+	return &RdDs{
+		KeyTag:     enc.Uint16(buf[0:2]),
+		Algorithm:  buf[2],
+		DigestType: buf[3],
+		Digest:     buf[4:],
+	}, nil
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdDs) PrintTo(out *bytes.Buffer) {
+	fmt.Fprintf(out, "%d %d %d %X",
+		d.KeyTag, d.Algorithm, d.DigestType, d.Digest)
+}
+
+// Pack packs the record.
+func (d *RdDs) Pack() []byte {
+	ret := make([]byte, 4, 4+len(d.Digest))
+	enc.PutUint16(ret[0:2], d.KeyTag)
+	ret[2] = d.Algorithm
+	ret[3] = d.DigestType
+	return append(ret, d.Digest...)
+}
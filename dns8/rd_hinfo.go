@@ -0,0 +1,49 @@
+package dns8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// RdHinfo is an HINFO rdata, the cpu and the os of a host.
+type RdHinfo struct {
+	CPU []byte
+	OS  []byte
+}
+
+// UnpackRdHinfo unpacks an HINFO record.
+func UnpackRdHinfo(in *bytes.Reader, n uint16) (*RdHinfo, error) {
+	buf := make([]byte, n)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+
+	cpu, left, e := unpackCharString(buf)
+	if e != nil {
+		return nil, e
+	}
+	os, left, e := unpackCharString(left)
+	if e != nil {
+		return nil, e
+	}
+	if len(left) > 0 {
+		return nil, fmt.Errorf("hinfo with %d extra bytes", len(left))
+	}
+
+	return &RdHinfo{CPU: cpu, OS: os}, nil
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdHinfo) PrintTo(out *bytes.Buffer) {
+	quoteCharString(out, d.CPU)
+	out.WriteByte(' ')
+	quoteCharString(out, d.OS)
+}
+
+// Pack packs the record.
+func (d *RdHinfo) Pack() []byte {
+	buf := new(bytes.Buffer)
+	packCharString(buf, d.CPU)
+	packCharString(buf, d.OS)
+	return buf.Bytes()
+}
@@ -32,3 +32,19 @@ func (d RdIPv6) PrintTo(out *bytes.Buffer) {
 func (d RdIPv6) Pack() []byte {
 	return net.IP(d).To16()
 }
+
+// RdToIPv6 converts rdata to IPv6 address
+func RdToIPv6(r Rdata) net.IP {
+	return (net.IP)(r.(RdIPv6))
+}
+
+// RdToIP converts an A or AAAA rdata to IP address
+func RdToIP(r Rdata) net.IP {
+	switch r := r.(type) {
+	case RdIPv4:
+		return net.IP(r)
+	case RdIPv6:
+		return net.IP(r)
+	}
+	panic("not an ip rdata")
+}
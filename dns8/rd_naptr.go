@@ -0,0 +1,76 @@
+package dns8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// RdNaptr is a NAPTR rdata, a rewrite rule of RFC 3403.
+type RdNaptr struct {
+	Order       uint16
+	Preference  uint16
+	Flags       []byte
+	Services    []byte
+	Regexp      []byte
+	Replacement *Domain
+}
+
+// UnpackRdNaptr unpacks a NAPTR record.
+func UnpackRdNaptr(in *bytes.Reader, n uint16, p []byte) (*RdNaptr, error) {
+	if n <= 4 {
+		return nil, fmt.Errorf("naptr with %d bytes", n)
+	}
+
+	// the replacement is not compressed, so the strings end before it
+	buf := make([]byte, n)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+
+	ret := &RdNaptr{
+		Order:      enc.Uint16(buf[0:2]),
+		Preference: enc.Uint16(buf[2:4]),
+	}
+	left := buf[4:]
+	var e error
+	for _, s := range []*[]byte{&ret.Flags, &ret.Services, &ret.Regexp} {
+		if *s, left, e = unpackCharString(left); e != nil {
+			return nil, e
+		}
+	}
+
+	r := bytes.NewReader(left)
+	ret.Replacement, _, e = unpackDomainLen(r, p)
+	if e != nil {
+		return nil, e
+	}
+	if r.Len() > 0 {
+		return nil, fmt.Errorf("naptr with %d extra bytes", r.Len())
+	}
+
+	return ret, nil
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdNaptr) PrintTo(out *bytes.Buffer) {
+	fmt.Fprintf(out, "%d %d ", d.Order, d.Preference)
+	for _, s := range [][]byte{d.Flags, d.Services, d.Regexp} {
+		quoteCharString(out, s)
+		out.WriteByte(' ')
+	}
+	fmt.Fprint(out, d.Replacement)
+}
+
+// Pack packs the record.
+func (d *RdNaptr) Pack() []byte {
+	buf := new(bytes.Buffer)
+	b := make([]byte, 4)
+	enc.PutUint16(b[0:2], d.Order)
+	enc.PutUint16(b[2:4], d.Preference)
+	buf.Write(b)
+	for _, s := range [][]byte{d.Flags, d.Services, d.Regexp} {
+		packCharString(buf, s)
+	}
+	d.Replacement.Pack(buf)
+	return buf.Bytes()
+}
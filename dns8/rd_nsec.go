@@ -0,0 +1,52 @@
+package dns8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// RdNsec is an NSEC rdata. It proves that no name exists between the
+// owner and the next domain, and lists the types of the owner.
+type RdNsec struct {
+	NextDomain *Domain
+	Types      []uint16
+}
+
+// UnpackRdNsec unpacks an NSEC record.
+func UnpackRdNsec(in *bytes.Reader, n uint16, p []byte) (*RdNsec, error) {
+	next, used, e := unpackDomainLen(in, p)
+	if e != nil {
+		return nil, e
+	}
+	if used > int(n) {
+		return nil, fmt.Errorf("nsec next domain overflows")
+	}
+
+	buf := make([]byte, int(n)-used)
+	if _, e := in.Read(buf); e != nil && len(buf) > 0 {
+		return nil, e
+	}
+	types, e := unpackTypeBitmap(buf)
+	if e != nil {
+		return nil, e
+	}
+
+	return &RdNsec{NextDomain: next, Types: types}, nil
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdNsec) PrintTo(out *bytes.Buffer) {
+	fmt.Fprint(out, d.NextDomain)
+	printTypes(out, d.Types)
+}
+
+// Pack packs the record.
+func (d *RdNsec) Pack() []byte {
+	buf := new(bytes.Buffer)
+	d.NextDomain.Pack(buf)
+	buf.Write(packTypeBitmap(d.Types))
+	return buf.Bytes()
+}
+
+// HasType checks if the type is listed in the bitmap.
+func (d *RdNsec) HasType(t uint16) bool { return hasType(d.Types, t) }
@@ -0,0 +1,163 @@
+package dns8
+
+import (
+	"bytes"
+	"encoding/base32"
+	"errors"
+	"fmt"
+)
+
+// Nsec3OptOut is the NSEC3 flag for opt-out, RFC 5155.
+const Nsec3OptOut = 0x01
+
+// nsec3Hash is the base32 encoding for NSEC3 hashed names.
+var nsec3Hash = base32.HexEncoding.WithPadding(base32.NoPadding)
+
+// RdNsec3 is an NSEC3 rdata. It proves that no hashed name exists
+// between the hashed owner and the next hashed owner.
+type RdNsec3 struct {
+	HashAlgorithm uint8
+	Flags         uint8
+	Iterations    uint16
+	Salt          []byte
+	NextHashed    []byte
+	Types         []uint16
+}
+
+// RdNsec3Param is an NSEC3PARAM rdata, the hash parameters of a zone.
+type RdNsec3Param struct {
+	HashAlgorithm uint8
+	Flags         uint8
+	Iterations    uint16
+	Salt          []byte
+}
+
+// unpackNsec3Head unpacks the fields shared by NSEC3 and NSEC3PARAM,
+// and returns the bytes left.
+func unpackNsec3Head(buf []byte, h *RdNsec3Param) ([]byte, error) {
+	if len(buf) < 5 {
+		return nil, errors.New("nsec3 too short")
+	}
+	h.HashAlgorithm = buf[0]
+	h.Flags = buf[1]
+	h.Iterations = enc.Uint16(buf[2:4])
+
+	n := int(buf[4])
+	if len(buf) < 5+n {
+		return nil, errors.New("nsec3 salt overflows")
+	}
+	h.Salt = buf[5 : 5+n]
+	return buf[5+n:], nil
+}
+
+func (h *RdNsec3Param) packHead(buf *bytes.Buffer) {
+	b := make([]byte, 5)
+	b[0] = h.HashAlgorithm
+	b[1] = h.Flags
+	enc.PutUint16(b[2:4], h.Iterations)
+	b[4] = byte(len(h.Salt))
+	buf.Write(b)
+	buf.Write(h.Salt)
+}
+
+func saltString(salt []byte) string {
+	if len(salt) == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%X", salt)
+}
+
+// UnpackRdNsec3 unpacks an NSEC3 record.
+func UnpackRdNsec3(in *bytes.Reader, n uint16) (*RdNsec3, error) {
+	buf := make([]byte, n)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+
+	var h RdNsec3Param
+	buf, e := unpackNsec3Head(buf, &h)
+	if e != nil {
+		return nil, e
+	}
+	if len(buf) < 1 || len(buf) < 1+int(buf[0]) {
+		return nil, errors.New("nsec3 next hashed owner overflows")
+	}
+	next := buf[1 : 1+int(buf[0])]
+	types, e := unpackTypeBitmap(buf[1+len(next):])
+	if e != nil {
+		return nil, e
+	}
+
+	return &RdNsec3{
+		HashAlgorithm: h.HashAlgorithm,
+		Flags:         h.Flags,
+		Iterations:    h.Iterations,
+		Salt:          h.Salt,
+		NextHashed:    next,
+		Types:         types,
+	}, nil
+}
+
+func (d *RdNsec3) param() *RdNsec3Param {
+	return &RdNsec3Param{
+		HashAlgorithm: d.HashAlgorithm,
+		Flags:         d.Flags,
+		Iterations:    d.Iterations,
+		Salt:          d.Salt,
+	}
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdNsec3) PrintTo(out *bytes.Buffer) {
+	fmt.Fprintf(out, "%d %d %d %s %s",
+		d.HashAlgorithm, d.Flags, d.Iterations, saltString(d.Salt),
+		nsec3Hash.EncodeToString(d.NextHashed))
+	printTypes(out, d.Types)
+}
+
+// Pack packs the record.
+func (d *RdNsec3) Pack() []byte {
+	buf := new(bytes.Buffer)
+	d.param().packHead(buf)
+	buf.WriteByte(byte(len(d.NextHashed)))
+	buf.Write(d.NextHashed)
+	buf.Write(packTypeBitmap(d.Types))
+	return buf.Bytes()
+}
+
+// HasType checks if the type is listed in the bitmap.
+func (d *RdNsec3) HasType(t uint16) bool { return hasType(d.Types, t) }
+
+// OptOut checks if the opt-out flag is set.
+func (d *RdNsec3) OptOut() bool { return d.Flags&Nsec3OptOut != 0 }
+
+// UnpackRdNsec3Param unpacks an NSEC3PARAM record.
+func UnpackRdNsec3Param(in *bytes.Reader, n uint16) (*RdNsec3Param, error) {
+	buf := make([]byte, n)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+
+	ret := new(RdNsec3Param)
+	left, e := unpackNsec3Head(buf, ret)
+	if e != nil {
+		return nil, e
+	}
+	if len(left) > 0 {
+		return nil, fmt.Errorf("nsec3param with %d extra bytes", len(left))
+	}
+	return ret, nil
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdNsec3Param) PrintTo(out *bytes.Buffer) {
+	fmt.Fprintf(out, "%d %d %d %s",
+		d.HashAlgorithm, d.Flags, d.Iterations, saltString(d.Salt))
+}
+
+// Pack packs the record.
+func (d *RdNsec3Param) Pack() []byte {
+	buf := new(bytes.Buffer)
+	d.packHead(buf)
+	return buf.Bytes()
+}
@@ -0,0 +1,108 @@
+package dns8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// EDNS0 option codes
+const (
+	OptNSID         = 3
+	OptClientSubnet = 8
+	OptExpire       = 9
+	OptCookie       = 10
+	OptKeepAlive    = 11
+	OptPadding      = 12
+)
+
+var optStrings = map[uint16]string{
+	OptNSID:         "nsid",
+	OptClientSubnet: "subnet",
+	OptExpire:       "expire",
+	OptCookie:       "cookie",
+	OptKeepAlive:    "keepalive",
+	OptPadding:      "padding",
+}
+
+// OptString returns the string of an EDNS0 option code
+func OptString(code uint16) string {
+	s, found := optStrings[code]
+	if found {
+		return s
+	}
+	return fmt.Sprintf("opt%d", code)
+}
+
+// EdnsOption is an option in an OPT record
+type EdnsOption struct {
+	Code uint16
+	Data []byte
+}
+
+func (o *EdnsOption) String() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "%s=", OptString(o.Code))
+	RdBytes(o.Data).PrintTo(buf)
+	return buf.String()
+}
+
+// RdOpt is the rdata of an OPT pseudo-record, a list of options
+type RdOpt []*EdnsOption
+
+var _ Rdata = RdOpt(nil)
+
+// UnpackRdOpt unpacks the options of an OPT record
+func UnpackRdOpt(in *bytes.Reader, n uint16) (RdOpt, error) {
+	ret := make(RdOpt, 0, 2)
+
+	for in.Len() > 0 {
+		if in.Len() < 4 {
+			return nil, fmt.Errorf("option with %d bytes", in.Len())
+		}
+
+		buf := make([]byte, 4)
+		if _, e := in.Read(buf); e != nil {
+			return nil, e
+		}
+
+		opt := &EdnsOption{Code: enc.Uint16(buf[0:2])}
+		size := int(enc.Uint16(buf[2:4]))
+		if size > in.Len() {
+			return nil, fmt.Errorf("option %d with %d bytes", opt.Code, size)
+		}
+
+		opt.Data = make([]byte, size)
+		if size > 0 {
+			if _, e := in.Read(opt.Data); e != nil {
+				return nil, e
+			}
+		}
+
+		ret = append(ret, opt)
+	}
+
+	return ret, nil
+}
+
+// PrintTo prints the options
+func (d RdOpt) PrintTo(out *bytes.Buffer) {
+	for i, opt := range d {
+		if i > 0 {
+			fmt.Fprint(out, " ")
+		}
+		fmt.Fprint(out, opt)
+	}
+}
+
+// Pack packs the options
+func (d RdOpt) Pack() []byte {
+	buf := new(bytes.Buffer)
+	for _, opt := range d {
+		b := make([]byte, 4)
+		enc.PutUint16(b[0:2], opt.Code)
+		enc.PutUint16(b[2:4], uint16(len(opt.Data)))
+		buf.Write(b)
+		buf.Write(opt.Data)
+	}
+	return buf.Bytes()
+}
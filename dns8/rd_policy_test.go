@@ -0,0 +1,61 @@
+package dns8
+
+import (
+	"testing"
+)
+
+func TestPolicyRdata(t *testing.T) {
+	d := D("example.com")
+	rrs := []*RR{
+		{d, CAA, IN, 3600, &RdCaa{CaaCritical, "issue", []byte("ca.example.net")}},
+		{D("_443._tcp.example.com"), TLSA, IN, 3600,
+			&RdTlsa{3, 1, 1, []byte{0x0a, 0xbc}}},
+		{d, SSHFP, IN, 3600, &RdSshfp{4, 2, []byte{0x12, 0x34}}},
+		{D("1.2.0.192.in-addr.arpa"), PTR, IN, 3600, (*RdDomain)(d)},
+		{d, HINFO, IN, 3600, &RdHinfo{[]byte("x86"), []byte("my \"os\"")}},
+	}
+
+	p := &Packet{
+		Flag:     FlagResponse,
+		Question: &Question{d, CAA, IN},
+		Answer:   rrs,
+	}
+	got, e := Unpack(p.Pack())
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	expects := []string{
+		`example.com caa 128 issue "ca.example.net" 1h`,
+		"_443._tcp.example.com tlsa 3 1 1 0ABC 1h",
+		"example.com sshfp 4 2 1234 1h",
+		"1.2.0.192.in-addr.arpa ptr example.com 1h",
+		`example.com hinfo "x86" "my \"os\"" 1h`,
+	}
+	for i, rr := range got.Answer {
+		if s := rr.String(); s != expects[i] {
+			t.Errorf("expect %q, got %q", expects[i], s)
+		}
+	}
+	if !got.Answer[0].Rdata.(*RdCaa).Critical() {
+		t.Error("expect the critical flag")
+	}
+}
+
+func TestInfoCaa(t *testing.T) {
+	c := fakeInternet().NewClient()
+	defer c.Close()
+
+	info := NewInfo(D("www.example.com"))
+	info.CAA = true
+	if _, e := testCursor(c).T(info); e != nil {
+		t.Fatal(e)
+	}
+
+	if len(info.Caas) != 1 || !info.Caas[0].Domain.Equal(D("example.com")) {
+		t.Fatalf("expect the caa of example.com, got %v", info.Caas)
+	}
+	if tag := info.Caas[0].Rdata.(*RdCaa).Tag; tag != "issue" {
+		t.Errorf("expect tag issue, got %q", tag)
+	}
+}
@@ -0,0 +1,109 @@
+package dns8
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RdRrsig is an RRSIG rdata, a signature over a record set.
+type RdRrsig struct {
+	TypeCovered uint16
+	Algorithm   uint8
+	Labels      uint8
+	OrigTTL     uint32
+	Expiration  uint32 // seconds since 1970, in serial number arithmetic
+	Inception   uint32
+	KeyTag      uint16
+	SignerName  *Domain
+	Signature   []byte
+}
+
+const rrsigFixedLen = 18
+
+// unpackDomainLen unpacks a domain in rdata, where the labels are not
+// checked as a host name, and returns the bytes consumed in the rdata.
+func unpackDomainLen(in *bytes.Reader, p []byte) (*Domain, int, error) {
+	was := in.Len()
+	labels, e := UnpackLabels(in, p)
+	if e != nil {
+		return nil, 0, e
+	}
+	d := &Domain{strings.Join(labels, "."), labels}
+	return d, was - in.Len(), nil
+}
+
+// UnpackRdRrsig unpacks an RRSIG record.
+func UnpackRdRrsig(in *bytes.Reader, n uint16, p []byte) (*RdRrsig, error) {
+	if n <= rrsigFixedLen {
+		return nil, fmt.Errorf("rrsig with %d bytes", n)
+	}
+
+	buf := make([]byte, rrsigFixedLen)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+	ret := &RdRrsig{
+		TypeCovered: enc.Uint16(buf[0:2]),
+		Algorithm:   buf[2],
+		Labels:      buf[3],
+		OrigTTL:     enc.Uint32(buf[4:8]),
+		Expiration:  enc.Uint32(buf[8:12]),
+		Inception:   enc.Uint32(buf[12:16]),
+		KeyTag:      enc.Uint16(buf[16:18]),
+	}
+
+	signer, used, e := unpackDomainLen(in, p)
+	if e != nil {
+		return nil, e
+	}
+	if used > int(n)-rrsigFixedLen {
+		return nil, fmt.Errorf("rrsig signer name overflows")
+	}
+	ret.SignerName = signer
+
+	ret.Signature = make([]byte, int(n)-rrsigFixedLen-used)
+	if _, e := in.Read(ret.Signature); e != nil {
+		return nil, e
+	}
+
+	return ret, nil
+}
+
+func rrsigTime(t uint32) string {
+	return time.Unix(int64(t), 0).UTC().Format("20060102150405")
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdRrsig) PrintTo(out *bytes.Buffer) {
+	fmt.Fprintf(out, "%s %d %d %d %s %s %d %v %s",
+		TypeString(d.TypeCovered), d.Algorithm, d.Labels, d.OrigTTL,
+		rrsigTime(d.Expiration), rrsigTime(d.Inception),
+		d.KeyTag, d.SignerName,
+		base64.StdEncoding.EncodeToString(d.Signature))
+}
+
+// packHead packs the rdata without the signature, which is the head of
+// the signed data.
+func (d *RdRrsig) packHead(buf *bytes.Buffer) {
+	b := make([]byte, rrsigFixedLen)
+	enc.PutUint16(b[0:2], d.TypeCovered)
+	b[2] = d.Algorithm
+	b[3] = d.Labels
+	enc.PutUint32(b[4:8], d.OrigTTL)
+	enc.PutUint32(b[8:12], d.Expiration)
+	enc.PutUint32(b[12:16], d.Inception)
+	enc.PutUint16(b[16:18], d.KeyTag)
+	buf.Write(b)
+	d.SignerName.Pack(buf)
+}
+
+// Pack packs the record.
+func (d *RdRrsig) Pack() []byte {
+	buf := new(bytes.Buffer)
+	d.packHead(buf)
+	buf.Write(d.Signature)
+	return buf.Bytes()
+}
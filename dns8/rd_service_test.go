@@ -0,0 +1,130 @@
+package dns8
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestServiceRdata(t *testing.T) {
+	d := D("example.com")
+	rrs := []*RR{
+		{D("_sip._tcp.example.com"), SRV, IN, 3600,
+			&RdSrv{10, 60, 5060, D("sip.example.com")}},
+		{d, NAPTR, IN, 3600, &RdNaptr{
+			Order: 100, Preference: 10,
+			Flags: []byte("S"), Services: []byte("SIP+D2U"),
+			Replacement: D("_sip._udp.example.com"),
+		}},
+		{d, HTTPS, IN, 3600, &RdSvcb{1, Root, []*SvcParam{
+			{SvcAlpn, []byte("\x02h2\x02h3")},
+			{SvcPort, []byte{0x01, 0xbb}},
+			{SvcIPv4Hint, []byte{192, 0, 2, 1, 192, 0, 2, 2}},
+			{SvcECH, []byte("ech")},
+			{SvcIPv6Hint, net.ParseIP("2001:db8::1")},
+		}}},
+		{d, SVCB, IN, 3600, &RdSvcb{0, D("svc.example.net"), nil}},
+	}
+
+	p := &Packet{
+		Flag:     FlagResponse,
+		Question: &Question{d, HTTPS, IN},
+		Answer:   rrs,
+	}
+	got, e := Unpack(p.Pack())
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	expects := []string{
+		"_sip._tcp.example.com srv 10 60 5060 sip.example.com 1h",
+		`example.com naptr 100 10 "S" "SIP+D2U" "" _sip._udp.example.com 1h`,
+		"example.com https 1 . alpn=h2,h3 port=443 " +
+			"ipv4hint=192.0.2.1,192.0.2.2 ech=ZWNo ipv6hint=2001:db8::1 1h",
+		"example.com svcb 0 svc.example.net 1h",
+	}
+	for i, rr := range got.Answer {
+		if s := rr.String(); s != expects[i] {
+			t.Errorf("expect %q, got %q", expects[i], s)
+		}
+	}
+
+	https := got.Answer[2].Rdata.(*RdSvcb)
+	if alpn := https.Alpn(); len(alpn) != 2 || alpn[1] != "h3" {
+		t.Errorf("expect alpn h2 and h3, got %v", alpn)
+	}
+	if https.Port() != 443 || len(https.IPv4Hint()) != 2 {
+		t.Errorf("wrong port or hints: %d, %v", https.Port(), https.IPv4Hint())
+	}
+
+	if target := Target(got.Answer[2]); !target.Equal(d) {
+		t.Errorf("expect the owner as the target, got %v", target)
+	}
+	if target := Target(got.Answer[1]); target != nil {
+		t.Errorf("expect no target for naptr, got %v", target)
+	}
+}
+
+func TestRecurTargets(t *testing.T) {
+	c := fakeInternet().NewClient()
+	defer c.Close()
+
+	for _, test := range []struct {
+		d, ip string
+		t     uint16
+	}{
+		{"_sip._tcp.example.com", "10.0.2.2", SRV},
+		{"example.com", "10.0.2.1", HTTPS},
+		{"svc.example.com", "10.0.2.1", HTTPS}, // alias mode
+	} {
+		r := NewRecurType(D(test.d), test.t)
+		r.Targets = true
+		if _, e := testCursor(c).T(r); e != nil {
+			t.Fatal(e)
+		}
+		if r.Return != Okay || len(r.TargetIPs) != 1 {
+			t.Fatalf("%s: expect one target, got %v", test.d, r.TargetIPs)
+		}
+		for _, ips := range r.TargetIPs {
+			if len(ips) != 1 || !ips[0].Equal(net.ParseIP(test.ip)) {
+				t.Errorf("%s: expect %s, got %v", test.d, test.ip, ips)
+			}
+		}
+	}
+}
+
+func TestRecurTargetErrors(t *testing.T) {
+	c := fakeInternet().NewClient()
+	defer c.Close()
+
+	errFail := errors.New("fail")
+	resolve := targetResolve
+	defer func() { targetResolve = resolve }()
+	targetResolve = func(c Cursor, d *Domain) ([]net.IP, error) {
+		if d.Equal(D("sip.example.com")) {
+			return nil, errFail
+		}
+		return resolve(c, d)
+	}
+
+	r := NewRecurType(D("_xmpp._tcp.example.com"), SRV)
+	r.Targets = true
+	if _, e := testCursor(c).T(r); e != nil {
+		t.Fatal(e)
+	}
+	if e := r.TargetErrors["sip.example.com"]; e != errFail {
+		t.Errorf("expect the first target failed, got %v", e)
+	}
+	if ips := r.TargetIPs["example.com"]; len(ips) != 1 {
+		t.Errorf("expect the second target resolved, got %v", r.TargetIPs)
+	}
+
+	r = NewRecurType(D("loop.example.com"), HTTPS)
+	r.Targets = true
+	if _, e := testCursor(c).T(r); e != nil {
+		t.Fatal(e)
+	}
+	if e := r.TargetErrors["loop.example.com"]; e != errAliasChain {
+		t.Errorf("expect the alias loop stopped, got %v", e)
+	}
+}
@@ -0,0 +1,60 @@
+package dns8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// RdSrv is an SRV rdata, the location of a service, RFC 2782.
+type RdSrv struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   *Domain // root for no service
+}
+
+// UnpackRdSrv unpacks an SRV record.
+func UnpackRdSrv(in *bytes.Reader, n uint16, p []byte) (*RdSrv, error) {
+	if n <= 6 {
+		return nil, fmt.Errorf("srv with %d bytes", n)
+	}
+
+	buf := make([]byte, 6)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+	ret := &RdSrv{
+		Priority: enc.Uint16(buf[0:2]),
+		Weight:   enc.Uint16(buf[2:4]),
+		Port:     enc.Uint16(buf[4:6]),
+	}
+
+	target, used, e := unpackDomainLen(in, p)
+	if e != nil {
+		return nil, e
+	}
+	if used != int(n)-6 {
+		return nil, fmt.Errorf("srv target len expect %d, got %d",
+			int(n)-6, used)
+	}
+	ret.Target = target
+
+	return ret, nil
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdSrv) PrintTo(out *bytes.Buffer) {
+	fmt.Fprintf(out, "%d %d %d %v", d.Priority, d.Weight, d.Port, d.Target)
+}
+
+// Pack packs the record.
+func (d *RdSrv) Pack() []byte {
+	buf := new(bytes.Buffer)
+	b := make([]byte, 6)
+	enc.PutUint16(b[0:2], d.Priority)
+	enc.PutUint16(b[2:4], d.Weight)
+	enc.PutUint16(b[4:6], d.Port)
+	buf.Write(b)
+	d.Target.Pack(buf)
+	return buf.Bytes()
+}
@@ -0,0 +1,43 @@
+package dns8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// RdSshfp is an SSHFP rdata, the fingerprint of an ssh host key,
+// RFC 4255.
+type RdSshfp struct {
+	Algorithm   uint8
+	FpType      uint8
+	Fingerprint []byte
+}
+
+// UnpackRdSshfp unpacks an SSHFP record.
+func UnpackRdSshfp(in *bytes.Reader, n uint16) (*RdSshfp, error) {
+	if n <= 2 {
+		return nil, fmt.Errorf("sshfp with %d bytes", n)
+	}
+
+	buf := make([]byte, n)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+
+	return &RdSshfp{
+		Algorithm:   buf[0],
+		FpType:      buf[1],
+		Fingerprint: buf[2:],
+	}, nil
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdSshfp) PrintTo(out *bytes.Buffer) {
+	fmt.Fprintf(out, "%d %d %X", d.Algorithm, d.FpType, d.Fingerprint)
+}
+
+// Pack packs the record.
+func (d *RdSshfp) Pack() []byte {
+	ret := []byte{d.Algorithm, d.FpType}
+	return append(ret, d.Fingerprint...)
+}
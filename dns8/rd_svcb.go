@@ -0,0 +1,218 @@
+package dns8
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SvcParam keys, RFC 9460
+const (
+	SvcMandatory     = 0
+	SvcAlpn          = 1
+	SvcNoDefaultAlpn = 2
+	SvcPort          = 3
+	SvcIPv4Hint      = 4
+	SvcECH           = 5
+	SvcIPv6Hint      = 6
+	SvcDohPath       = 7
+)
+
+var svcKeyStrings = map[uint16]string{
+	SvcMandatory:     "mandatory",
+	SvcAlpn:          "alpn",
+	SvcNoDefaultAlpn: "no-default-alpn",
+	SvcPort:          "port",
+	SvcIPv4Hint:      "ipv4hint",
+	SvcECH:           "ech",
+	SvcIPv6Hint:      "ipv6hint",
+	SvcDohPath:       "dohpath",
+}
+
+// SvcKeyString returns the name of a SvcParam key.
+func SvcKeyString(k uint16) string {
+	if s, found := svcKeyStrings[k]; found {
+		return s
+	}
+	return fmt.Sprintf("key%d", k)
+}
+
+// SvcParam is a key value pair of a service binding.
+type SvcParam struct {
+	Key   uint16
+	Value []byte
+}
+
+// RdSvcb is an SVCB or HTTPS rdata, a service binding of RFC 9460.
+// A record with priority 0 is in the alias mode, where the target is
+// an alias of the owner.
+type RdSvcb struct {
+	Priority uint16
+	Target   *Domain // root for the owner itself in the service mode
+	Params   []*SvcParam
+}
+
+// UnpackRdSvcb unpacks an SVCB or HTTPS record.
+func UnpackRdSvcb(in *bytes.Reader, n uint16, p []byte) (*RdSvcb, error) {
+	if n <= 2 {
+		return nil, fmt.Errorf("svcb with %d bytes", n)
+	}
+
+	buf := make([]byte, 2)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+	ret := &RdSvcb{Priority: enc.Uint16(buf)}
+
+	target, _, e := unpackDomainLen(in, p)
+	if e != nil {
+		return nil, e
+	}
+	ret.Target = target
+
+	last := -1
+	for in.Len() > 0 {
+		head := make([]byte, 4)
+		if _, e := in.Read(head); e != nil {
+			return nil, e
+		}
+		key := enc.Uint16(head[0:2])
+		if int(key) <= last {
+			return nil, fmt.Errorf("svcb key %d out of order", key)
+		}
+		last = int(key)
+
+		value := make([]byte, enc.Uint16(head[2:4]))
+		if len(value) > in.Len() {
+			return nil, fmt.Errorf("svcb param %s overflows",
+				SvcKeyString(key))
+		}
+		in.Read(value)
+		ret.Params = append(ret.Params, &SvcParam{key, value})
+	}
+
+	return ret, nil
+}
+
+// Param returns the value of the key, or nil when not exists.
+func (d *RdSvcb) Param(k uint16) []byte {
+	for _, p := range d.Params {
+		if p.Key == k {
+			return p.Value
+		}
+	}
+	return nil
+}
+
+// Alpn returns the alpn protocol ids.
+func (d *RdSvcb) Alpn() []string {
+	var ret []string
+	v := d.Param(SvcAlpn)
+	for len(v) > 0 {
+		id, left, e := unpackCharString(v)
+		if e != nil {
+			break
+		}
+		ret = append(ret, string(id))
+		v = left
+	}
+	return ret
+}
+
+// Port returns the port, or 0 when not set.
+func (d *RdSvcb) Port() uint16 {
+	v := d.Param(SvcPort)
+	if len(v) != 2 {
+		return 0
+	}
+	return enc.Uint16(v)
+}
+
+func svcIPs(v []byte, n int) []net.IP {
+	var ret []net.IP
+	for len(v) >= n {
+		ret = append(ret, net.IP(v[:n]))
+		v = v[n:]
+	}
+	return ret
+}
+
+// IPv4Hint returns the ipv4 address hints.
+func (d *RdSvcb) IPv4Hint() []net.IP { return svcIPs(d.Param(SvcIPv4Hint), 4) }
+
+// IPv6Hint returns the ipv6 address hints.
+func (d *RdSvcb) IPv6Hint() []net.IP { return svcIPs(d.Param(SvcIPv6Hint), 16) }
+
+func (p *SvcParam) printValue(out *bytes.Buffer) {
+	v := p.Value
+	switch p.Key {
+	case SvcMandatory:
+		for i := 0; i+1 < len(v); i += 2 {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			out.WriteString(SvcKeyString(enc.Uint16(v[i : i+2])))
+		}
+	case SvcAlpn:
+		var ids []string
+		for len(v) > 0 {
+			id, left, e := unpackCharString(v)
+			if e != nil {
+				break
+			}
+			ids = append(ids, strings.Replace(string(id), ",", "\\,", -1))
+			v = left
+		}
+		out.WriteString(strings.Join(ids, ","))
+	case SvcPort:
+		if len(v) == 2 {
+			fmt.Fprint(out, enc.Uint16(v))
+		}
+	case SvcIPv4Hint, SvcIPv6Hint:
+		n := 4
+		if p.Key == SvcIPv6Hint {
+			n = 16
+		}
+		for i, ip := range svcIPs(v, n) {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			fmt.Fprint(out, ip)
+		}
+	case SvcECH:
+		out.WriteString(base64.StdEncoding.EncodeToString(v))
+	default:
+		quoteCharString(out, v)
+	}
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdSvcb) PrintTo(out *bytes.Buffer) {
+	fmt.Fprintf(out, "%d %v", d.Priority, d.Target)
+	for _, p := range d.Params {
+		fmt.Fprintf(out, " %s", SvcKeyString(p.Key))
+		if p.Key == SvcNoDefaultAlpn && len(p.Value) == 0 {
+			continue
+		}
+		out.WriteByte('=')
+		p.printValue(out)
+	}
+}
+
+// Pack packs the record.
+func (d *RdSvcb) Pack() []byte {
+	buf := new(bytes.Buffer)
+	b := make([]byte, 4)
+	enc.PutUint16(b[0:2], d.Priority)
+	buf.Write(b[0:2])
+	d.Target.Pack(buf)
+	for _, p := range d.Params {
+		enc.PutUint16(b[0:2], p.Key)
+		enc.PutUint16(b[2:4], uint16(len(p.Value)))
+		buf.Write(b)
+		buf.Write(p.Value)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,46 @@
+package dns8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// RdTlsa is a TLSA rdata, the certificate association of DANE,
+// RFC 6698.
+type RdTlsa struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Data         []byte
+}
+
+// UnpackRdTlsa unpacks a TLSA record.
+func UnpackRdTlsa(in *bytes.Reader, n uint16) (*RdTlsa, error) {
+	if n <= 3 {
+		return nil, fmt.Errorf("tlsa with %d bytes", n)
+	}
+
+	buf := make([]byte, n)
+	if _, e := in.Read(buf); e != nil {
+		return nil, e
+	}
+
+	return &RdTlsa{
+		Usage:        buf[0],
+		Selector:     buf[1],
+		MatchingType: buf[2],
+		Data:         buf[3:],
+	}, nil
+}
+
+// PrintTo prints the record in the presentation format.
+func (d *RdTlsa) PrintTo(out *bytes.Buffer) {
+	fmt.Fprintf(out, "%d %d %d %X",
+		d.Usage, d.Selector, d.MatchingType, d.Data)
+}
+
+// Pack packs the record.
+func (d *RdTlsa) Pack() []byte {
+	ret := []byte{d.Usage, d.Selector, d.MatchingType}
+	return append(ret, d.Data...)
+}
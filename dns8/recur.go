@@ -1,12 +1,13 @@
 package dns8
 
 import (
+	"errors"
 	"net"
 )
 
 var nsResolve func(c Cursor, d *Domain, zs *ZoneServers) ([]net.IP, error)
 
-var recurCache = NewCache()
+var targetResolve func(c Cursor, d *Domain) ([]net.IP, error)
 
 // Recur is a recursive query task that searches
 // for the domain and type that starts with a zone servers
@@ -15,14 +16,20 @@ type Recur struct {
 	Type      uint16
 	StartWith *ZoneServers
 	HeadLess  bool
+	Edns      *Edns // EDNS0 setting of the queries, nil for the term's
+	Targets   bool  // resolve the targets of SRV, SVCB and HTTPS answers
 
 	Return  int          // valid when Error is not null
-	Packet  *Packet      // valid when Return is Okay
-	EndWith *ZoneServers // valid when Return is Okay
+	Packet  *Packet      // valid when Return is Okay or NotExists
+	EndWith *ZoneServers // valid when Return is Okay or NotExists
 	Answers []*RR        // the records in Packet that ends the query
 	Zones   []*ZoneServers
 
-	zone *ZoneServers
+	TargetIPs    map[string][]net.IP // addresses of the targets, when Targets
+	TargetErrors map[string]error    // targets that fail to resolve
+
+	zone    *ZoneServers
+	aliases int // the number of aliases followed to this query
 }
 
 // NewRecur creates a new recursive query for the
@@ -52,17 +59,32 @@ const (
 	Lost      // no valid server reachable
 )
 
-func (r *Recur) begin() *ZoneServers {
+func (r *Recur) begin(c Cursor) *ZoneServers {
 	if r.StartWith != nil {
 		return r.StartWith
 	}
 
-	cached := recurCache.Get(r.Domain.Registrar())
+	var delegated *ZoneServers
+	if cache := c.Config().RRCache; cache != nil {
+		delegated = cache.Delegation(r.Domain)
+	}
+
+	var cached *ZoneServers
+	if cache := c.Config().Cache; cache != nil {
+		cached = cache.Get(r.Domain.Registrar())
+	}
 	if cached != nil {
-		return cached
+		if delegated == nil || !cached.Zone().IsZoneOf(delegated.Zone()) {
+			return cached
+		}
+	}
+
+	if delegated != nil {
+		c.P().Printf("// cached delegation: %v", delegated.Zone())
+		return delegated
 	}
 
-	return roots
+	return c.Config().roots()
 }
 
 // Run executes the recursive query using the cursor.
@@ -74,8 +96,9 @@ func (r *Recur) Run(c Cursor) {
 		defer p.ShiftOut("}")
 	}
 
-	r.zone = r.begin()
+	r.zone = r.begin(c)
 	r.Zones = make([]*ZoneServers, 0, 100)
+	cache := c.Config().Cache
 
 	for r.zone != nil {
 		next, e := r.query(c)
@@ -83,9 +106,119 @@ func (r *Recur) Run(c Cursor) {
 			return
 		}
 
-		recurCache.Put(r.zone)
+		if cache != nil {
+			cache.Put(r.zone)
+		}
 		r.zone = next
 	}
+
+	if r.Targets && r.Return == Okay {
+		r.resolveTargets(c)
+	}
+}
+
+// Target returns the target host of an SRV, SVCB or HTTPS record, or
+// nil when the record has no target or is of other types.
+func Target(rr *RR) *Domain {
+	switch rd := rr.Rdata.(type) {
+	case *RdSrv:
+		if rd.Target.IsRoot() {
+			return nil // service not available
+		}
+		return rd.Target
+	case *RdSvcb:
+		if !rd.Target.IsRoot() {
+			return rd.Target
+		}
+		if rd.Priority == 0 {
+			return nil // alias to nothing
+		}
+		return rr.Domain // the owner itself
+	}
+	return nil
+}
+
+// isAlias checks if the record is an SVCB or HTTPS record in alias
+// mode that has a target.
+func isAlias(rr *RR) bool {
+	rd, ok := rr.Rdata.(*RdSvcb)
+	return ok && rd.Priority == 0 && !rd.Target.IsRoot()
+}
+
+// maxAliasChain limits the SVCB and HTTPS aliases followed.
+const maxAliasChain = 8
+
+var (
+	errAliasChain = errors.New("alias chain too long")
+	errAlias      = errors.New("alias not resolved")
+)
+
+// resolveTargets resolves the addresses of the targets. A target that
+// fails is recorded in TargetErrors, and the others go on.
+func (r *Recur) resolveTargets(c Cursor) {
+	if targetResolve == nil {
+		return
+	}
+
+	r.TargetIPs = make(map[string][]net.IP)
+	r.TargetErrors = make(map[string]error)
+	seen := make(map[string]bool)
+	for _, rr := range r.Answers {
+		d := Target(rr)
+		if d == nil {
+			continue
+		}
+		k := d.key()
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		if isAlias(rr) {
+			r.followAlias(c, d, rr.Type)
+			continue
+		}
+
+		ips, e := targetResolve(c, d)
+		if e != nil {
+			r.TargetErrors[k] = e
+			c.P().Printf("// target: %v error: %v", d, e)
+			continue
+		}
+		r.TargetIPs[k] = ips
+		c.P().Printf("// target: %v %v", d, ips)
+	}
+}
+
+// followAlias queries the alias target for records of type t, as in
+// RFC 9460 section 2.4.2, and takes in the targets of the answers.
+func (r *Recur) followAlias(c Cursor, d *Domain, t uint16) {
+	k := d.key()
+	if r.aliases >= maxAliasChain {
+		r.TargetErrors[k] = errAliasChain
+		c.P().Printf("// alias: %v error: %v", d, errAliasChain)
+		return
+	}
+
+	alias := NewRecurType(d, t)
+	alias.Edns = r.Edns
+	alias.Targets = true
+	alias.aliases = r.aliases + 1
+	if _, e := c.T(alias); e != nil {
+		r.TargetErrors[k] = e
+		return
+	}
+	if alias.Return != Okay {
+		r.TargetErrors[k] = errAlias
+		return
+	}
+
+	for target, ips := range alias.TargetIPs {
+		r.TargetIPs[target] = ips
+	}
+	for target, e := range alias.TargetErrors {
+		r.TargetErrors[target] = e
+	}
 }
 
 func (r *Recur) q(c Cursor, ip net.IP, s *Domain) (*ZoneServers, error) {
@@ -95,6 +228,7 @@ func (r *Recur) q(c Cursor, ip net.IP, s *Domain) (*ZoneServers, error) {
 		Server:     Server(ip),
 		Zone:       r.zone.Zone(),
 		ServerName: s,
+		Edns:       r.Edns,
 	}
 
 	reply, e := c.Q(q)
@@ -104,9 +238,14 @@ func (r *Recur) q(c Cursor, ip net.IP, s *Domain) (*ZoneServers, error) {
 
 	attempt := reply.Last()
 
-	if attempt.Error != nil {
+	switch reply.LastEnd() {
+	case EndTimeout, EndError:
 		c.P().Printf("// unreachable: %v, last error %v", s, attempt.Error)
 		return nil, nil
+	case EndRcode:
+		c.P().Printf("// server failure: %v, rcode=%d",
+			s, attempt.Recv.Packet.Rcode())
+		return nil, nil
 	}
 
 	p := attempt.Recv.Packet
@@ -129,6 +268,8 @@ func (r *Recur) q(c Cursor, ip net.IP, s *Domain) (*ZoneServers, error) {
 	next := Servers(p, r.zone.Zone(), r.Domain, c.P())
 	if next == nil {
 		r.Return = NotExists
+		r.Packet = p
+		r.EndWith = r.zone
 		c.P().Print("// record does not exist")
 	}
 
@@ -139,19 +280,36 @@ func (r *Recur) query(c Cursor) (*ZoneServers, error) {
 	zone := r.zone
 	r.Zones = append(r.Zones, zone)
 	resolved, unresolved := zone.Prepare()
+	cfg := c.Config()
+	resolved, unresolved = policyServers(cfg.IPPolicy, resolved, unresolved)
+	if cfg.ServerOrder != nil {
+		resolved = cfg.ServerOrder.Order(resolved)
+	}
 
 	c.P().Printf("// zone: %v", zone.Zone())
 
-	// try resolved servers first
-	for _, server := range resolved {
-		next, e := r.q(c, server.IP, server.Domain)
-		if e != nil || next != nil || r.Return != Working {
-			return next, e
+	rounds := cfg.Retry.rounds()
+	for round := 0; round < rounds; round++ {
+		if round > 0 {
+			c.P().Print("// retry all servers")
+			// when canceled, the next query stops with the error
+			sleepContext(c.Context(), cfg.Retry.wait(round))
 		}
-	}
 
-	if nsResolve != nil {
-		// when all resolved failed, we try unresolved ones
+		// try resolved servers first
+		for _, server := range resolved {
+			next, e := r.q(c, server.IP, server.Domain)
+			if e != nil || next != nil || r.Return != Working {
+				return next, e
+			}
+		}
+
+		if round > 0 || nsResolve == nil {
+			continue
+		}
+
+		// when all resolved failed, we try unresolved ones,
+		// and keep them for the next rounds
 		for _, server := range unresolved {
 			bugOn(server.IP != nil)
 
@@ -165,6 +323,12 @@ func (r *Recur) query(c Cursor) (*ZoneServers, error) {
 				if e != nil || next != nil || r.Return != Working {
 					return next, e
 				}
+
+				resolved = append(resolved, &NameServer{
+					Zone:   zone.Zone(),
+					Domain: server.Domain,
+					IP:     ip,
+				})
 			}
 		}
 	}
@@ -0,0 +1,130 @@
+package dns8
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RdataUnpacker unpacks an rdata of n bytes from in, where p is the
+// whole packet for the compressed domain names.
+type RdataUnpacker func(in *bytes.Reader, n uint16, p []byte) (Rdata, error)
+
+var (
+	// registry guards the type names and the rdata codecs, which
+	// library users may register at any time
+	registry sync.RWMutex
+
+	typeCodes      = make(map[string]uint16)
+	rdataUnpackers = make(map[uint16]RdataUnpacker)
+)
+
+func init() {
+	for t, s := range typeStrings {
+		typeCodes[s] = t
+	}
+}
+
+// RegisterRdata registers a type, so that its records of class IN are
+// unpacked by unpack, and printed and parsed with name. An empty name
+// keeps the current name of the type, and a nil unpack keeps the
+// current codec. It is usually called in init.
+func RegisterRdata(t uint16, name string, unpack RdataUnpacker) error {
+	name = strings.ToLower(name)
+	if name != "" {
+		if _, e := parseNumbered(name, "type", "t"); e == nil {
+			return fmt.Errorf("type name %q is reserved", name)
+		}
+		if strings.ContainsAny(name, " \t\n\\\"") {
+			return fmt.Errorf("invalid type name %q", name)
+		}
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if name != "" {
+		if other, found := typeCodes[name]; found && other != t {
+			return fmt.Errorf("type name %q is used by %s", name,
+				typeStrings[other])
+		}
+		delete(typeCodes, typeStrings[t])
+		typeStrings[t] = name
+		typeCodes[name] = t
+	}
+	if unpack != nil {
+		rdataUnpackers[t] = unpack
+	}
+	return nil
+}
+
+func rdataUnpacker(t uint16) RdataUnpacker {
+	registry.RLock()
+	defer registry.RUnlock()
+	return rdataUnpackers[t]
+}
+
+// parseNumbered parses the generic form of a code, like type65 or
+// t65, with any of the prefixes.
+func parseNumbered(s string, prefixes ...string) (uint16, error) {
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(s, prefix) {
+			continue
+		}
+		digits := s[len(prefix):]
+		if digits == "" || digits[0] < '0' || digits[0] > '9' {
+			continue
+		}
+		ret, e := strconv.ParseUint(digits, 10, 16)
+		if e != nil {
+			return 0, e
+		}
+		return uint16(ret), nil
+	}
+	return 0, fmt.Errorf("%q is not numbered", s)
+}
+
+// ParseType parses a type name, like MX, or the generic form of
+// RFC 3597, like TYPE65. It ignores the letter cases.
+func ParseType(s string) (uint16, error) {
+	s = strings.ToLower(s)
+	if s == "*" {
+		return ANY, nil
+	}
+
+	registry.RLock()
+	t, found := typeCodes[s]
+	registry.RUnlock()
+	if found {
+		return t, nil
+	}
+
+	t, e := parseNumbered(s, "type", "t")
+	if e != nil {
+		return 0, fmt.Errorf("unknown type %q", s)
+	}
+	return t, nil
+}
+
+// ParseClass parses a class name, like IN, or the generic form of
+// RFC 3597, like CLASS1. It ignores the letter cases.
+func ParseClass(s string) (uint16, error) {
+	s = strings.ToLower(s)
+	if s == "*" {
+		return ANY, nil
+	}
+
+	for c, name := range classStrings {
+		if name == s {
+			return c, nil
+		}
+	}
+
+	c, e := parseNumbered(s, "class", "c")
+	if e != nil {
+		return 0, fmt.Errorf("unknown class %q", s)
+	}
+	return c, nil
+}
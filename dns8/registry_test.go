@@ -0,0 +1,115 @@
+package dns8
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParseType(t *testing.T) {
+	for _, test := range []struct {
+		s string
+		t uint16
+	}{
+		{"mx", MX},
+		{"AAAA", AAAA},
+		{"nsap-ptr", NSAPPTR},
+		{"TYPE65", HTTPS},
+		{"t257", CAA},
+		{"type65280", 65280},
+		{"*", ANY},
+	} {
+		got, e := ParseType(test.s)
+		if e != nil {
+			t.Errorf("%q: %v", test.s, e)
+		} else if got != test.t {
+			t.Errorf("%q: expect %d, got %d", test.s, test.t, got)
+		}
+	}
+
+	for _, s := range []string{"", "nope", "type", "type70000", "t-1"} {
+		if _, e := ParseType(s); e == nil {
+			t.Errorf("%q: expect an error", s)
+		}
+	}
+
+	if s := TypeString(65280); s != "type65280" {
+		t.Errorf("expect type65280, got %q", s)
+	}
+	if c, e := ParseClass("CLASS3"); e != nil || c != CH {
+		t.Errorf("expect ch, got %d, %v", c, e)
+	}
+	if c, e := ParseClass("IN"); e != nil || c != IN {
+		t.Errorf("expect in, got %d, %v", c, e)
+	}
+}
+
+func TestRdBytes(t *testing.T) {
+	bs := RdBytes{0x0a, 0x00, 0x00, 0x01}
+	buf := new(bytes.Buffer)
+	bs.PrintTo(buf)
+	if s := buf.String(); s != `\# 4 0a000001` {
+		t.Fatalf("expect generic format, got %q", s)
+	}
+
+	got, e := ParseRdBytes(`\# 4 0a00 0001`)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !bytes.Equal(got, bs) {
+		t.Errorf("expect %x, got %x", []byte(bs), []byte(got))
+	}
+
+	for _, s := range []string{`\# 3 0a000001`, `# 4 0a000001`, `\# 1 zz`} {
+		if _, e := ParseRdBytes(s); e == nil {
+			t.Errorf("%q: expect an error", s)
+		}
+	}
+}
+
+type testRdata string
+
+func (d testRdata) PrintTo(out *bytes.Buffer) { out.WriteString(string(d)) }
+func (d testRdata) Pack() []byte              { return []byte(d) }
+
+func TestRegisterRdata(t *testing.T) {
+	const typ = 65281 // private use
+	e := RegisterRdata(typ, "TEST", func(in *bytes.Reader, n uint16,
+		p []byte) (Rdata, error) {
+		buf := make([]byte, n)
+		if _, e := in.Read(buf); e != nil {
+			return nil, e
+		}
+		if !bytes.HasPrefix(buf, []byte("test")) {
+			return nil, errors.New("not a test")
+		}
+		return testRdata(buf), nil
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if got, e := ParseType("test"); e != nil || got != typ {
+		t.Errorf("expect %d, got %d, %v", typ, got, e)
+	}
+	if e := RegisterRdata(typ+1, "test", nil); e == nil {
+		t.Error("expect an error for a used name")
+	}
+	if e := RegisterRdata(typ+1, "type5", nil); e == nil {
+		t.Error("expect an error for a reserved name")
+	}
+
+	d := D("example.com")
+	p := &Packet{
+		Flag:     FlagResponse,
+		Question: &Question{d, typ, IN},
+		Answer:   []*RR{{d, typ, IN, 3600, testRdata("test data")}},
+	}
+	got, e := Unpack(p.Pack())
+	if e != nil {
+		t.Fatal(e)
+	}
+	if s := got.Answer[0].String(); s != "example.com test test data 1h" {
+		t.Errorf("unexpected record %q", s)
+	}
+}
@@ -0,0 +1,100 @@
+package dns8
+
+import (
+	"math"
+	"time"
+)
+
+// RetryPolicy configures how a failed query is retried.
+type RetryPolicy struct {
+	Attempts   int           // attempts on each server
+	Backoff    time.Duration // wait before the first retry, doubles each retry
+	MaxBackoff time.Duration // upper bound of the wait, 0 for no bound
+
+	// NextServer retries on the next server in the zone, rather than
+	// retrying the same server right away.
+	NextServer bool
+
+	// RcodeFail treats SERVFAIL, REFUSED and NOTIMP replies as failures
+	// of the server.
+	RcodeFail bool
+}
+
+// DefaultRetryPolicy returns the default retry policy.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:   3,
+		Backoff:    time.Millisecond * 50,
+		MaxBackoff: time.Second,
+		NextServer: true,
+		RcodeFail:  true,
+	}
+}
+
+func (r *RetryPolicy) attempts() int {
+	if r.Attempts < 1 {
+		return 1
+	}
+	return r.Attempts
+}
+
+// sameServer returns the number of attempts on the same server
+// in one query.
+func (r *RetryPolicy) sameServer() int {
+	if r.NextServer {
+		return 1
+	}
+	return r.attempts()
+}
+
+// rounds returns the number of rounds to try all the servers in a zone.
+func (r *RetryPolicy) rounds() int {
+	if r.NextServer {
+		return r.attempts()
+	}
+	return 1
+}
+
+// wait returns the time to wait before the i-th retry.
+func (r *RetryPolicy) wait(i int) time.Duration {
+	if i <= 0 {
+		return 0
+	}
+
+	ret := r.Backoff
+	for j := 1; j < i; j++ {
+		if r.MaxBackoff > 0 && ret >= r.MaxBackoff {
+			break
+		}
+		if ret > math.MaxInt64/2 {
+			break // doubling overflows
+		}
+		ret *= 2
+	}
+
+	if r.MaxBackoff > 0 && ret > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+	return ret
+}
+
+func isServerFailure(rcode uint16) bool {
+	switch rcode {
+	case RcodeServerFail, RcodeRefused, RcodeNotImplement:
+		return true
+	}
+	return false
+}
+
+// end judges how an attempt ended.
+func (r *RetryPolicy) end(x *Exchange) int {
+	switch {
+	case x.Timeout():
+		return EndTimeout
+	case x.Error != nil:
+		return EndError
+	case r.RcodeFail && isServerFailure(x.Recv.Packet.Rcode()):
+		return EndRcode
+	}
+	return EndReply
+}
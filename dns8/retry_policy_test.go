@@ -0,0 +1,95 @@
+package dns8
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryRounds(t *testing.T) {
+	for _, test := range []struct {
+		attempts   int
+		nextServer bool
+		same       int
+		rounds     int
+	}{
+		{0, false, 1, 1}, // at least one attempt
+		{0, true, 1, 1},
+		{3, false, 3, 1}, // all the attempts on one server first
+		{3, true, 1, 3},  // one attempt on each server in a round
+	} {
+		r := &RetryPolicy{Attempts: test.attempts, NextServer: test.nextServer}
+		if same := r.sameServer(); same != test.same {
+			t.Errorf("%+v: expect %d on the same server, got %d",
+				*r, test.same, same)
+		}
+		if rounds := r.rounds(); rounds != test.rounds {
+			t.Errorf("%+v: expect %d rounds, got %d",
+				*r, test.rounds, rounds)
+		}
+		if n := r.sameServer() * r.rounds(); n != r.attempts() {
+			t.Errorf("%+v: expect %d attempts per server, got %d",
+				*r, r.attempts(), n)
+		}
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	const ms = time.Millisecond
+
+	for _, test := range []struct {
+		backoff, max time.Duration
+		waits        []time.Duration // from the 0th retry
+	}{
+		{50 * ms, time.Second,
+			[]time.Duration{0, 50 * ms, 100 * ms, 200 * ms, 400 * ms}},
+		{50 * ms, 120 * ms,
+			[]time.Duration{0, 50 * ms, 100 * ms, 120 * ms, 120 * ms}},
+		{50 * ms, 0, // no bound
+			[]time.Duration{0, 50 * ms, 100 * ms, 200 * ms, 400 * ms}},
+		{0, time.Second, []time.Duration{0, 0, 0}},
+	} {
+		r := &RetryPolicy{Backoff: test.backoff, MaxBackoff: test.max}
+		for i, expect := range test.waits {
+			if got := r.wait(i); got != expect {
+				t.Errorf("backoff %v, max %v: retry %d expect %v, got %v",
+					test.backoff, test.max, i, expect, got)
+			}
+		}
+	}
+
+	// an unbounded wait stops doubling before it overflows
+	r := &RetryPolicy{Backoff: time.Hour}
+	if got := r.wait(100); got < r.wait(99) {
+		t.Errorf("expect the wait not to shrink, got %v", got)
+	}
+}
+
+func TestRetryEnd(t *testing.T) {
+	reply := func(rcode uint16) *Exchange {
+		return &Exchange{Recv: &Message{
+			Packet: &Packet{Flag: FlagResponse | rcode},
+		}}
+	}
+
+	for _, test := range []struct {
+		name      string
+		x         *Exchange
+		rcodeFail bool
+		end       int
+	}{
+		{"timeout", &Exchange{Error: errTimeout}, true, EndTimeout},
+		{"error", &Exchange{Error: errors.New("x")}, true, EndError},
+		{"okay", reply(RcodeOkay), true, EndReply},
+		{"name error", reply(RcodeNameError), true, EndReply},
+		{"servfail", reply(RcodeServerFail), true, EndRcode},
+		{"refused", reply(RcodeRefused), true, EndRcode},
+		{"notimp", reply(RcodeNotImplement), true, EndRcode},
+		{"servfail as a reply", reply(RcodeServerFail), false, EndReply},
+	} {
+		r := &RetryPolicy{RcodeFail: test.rcodeFail}
+		if end := r.end(test.x); end != test.end {
+			t.Errorf("%s: expect end %d, got %d", test.name, test.end, end)
+		}
+	}
+}
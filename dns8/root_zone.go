@@ -0,0 +1,196 @@
+package dns8
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RootZone is a local mirror of the root zone, loaded from a zone file
+// as in RFC 8806. It answers the queries to the root servers without
+// sending packets. A mirror is only used before the expiry of its SOA.
+type RootZone struct {
+	soa     *RR
+	records map[string][]*RR // by owner name
+	loaded  time.Time
+}
+
+// ParseRootZone parses a root zone file, like the root.zone from
+// www.internic.net/domain. Records other than NS, A, AAAA and SOA
+// are not needed for referrals, and are skipped.
+func ParseRootZone(r io.Reader) (*RootZone, error) {
+	ret := &RootZone{
+		records: make(map[string][]*RR),
+		loaded:  time.Now(),
+	}
+
+	e := readZone(r, func(l *zoneLine) error {
+		rr := &RR{Domain: l.owner, Class: IN, TTL: l.ttl}
+
+		switch l.typ {
+		case "ns":
+			if len(l.rdata) != 1 {
+				return l.errorf("invalid ns")
+			}
+			d, e := ParseDomain(l.rdata[0])
+			if e != nil {
+				return l.errorf("%v", e)
+			}
+			rr.Type, rr.Rdata = NS, (*RdDomain)(d)
+		case "a", "aaaa":
+			if len(l.rdata) != 1 {
+				return l.errorf("invalid address")
+			}
+			ip := net.ParseIP(l.rdata[0])
+			if ip == nil {
+				return l.errorf("invalid ip %q", l.rdata[0])
+			}
+			if ip4 := ip.To4(); ip4 != nil && l.typ == "a" {
+				rr.Type, rr.Rdata = A, RdIPv4(ip4)
+			} else if ip4 == nil && l.typ == "aaaa" {
+				rr.Type, rr.Rdata = AAAA, RdIPv6(ip)
+			} else {
+				return l.errorf("invalid ip %q", l.rdata[0])
+			}
+		case "soa":
+			if !l.owner.IsRoot() {
+				return l.errorf("soa not at the root")
+			}
+			if ret.soa != nil {
+				return l.errorf("duplicate soa")
+			}
+			soa, e := parseSoa(l.rdata)
+			if e != nil {
+				return l.errorf("%v", e)
+			}
+			rr.Type, rr.Rdata = SOA, soa
+			ret.soa = rr
+		default:
+			return nil
+		}
+
+		k := l.owner.key()
+		ret.records[k] = append(ret.records[k], rr)
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	if ret.soa == nil {
+		return nil, fmt.Errorf("root zone has no soa")
+	}
+	if len(ret.find(Root, NS)) == 0 {
+		return nil, fmt.Errorf("root zone has no ns")
+	}
+	return ret, nil
+}
+
+// LoadRootZone loads a root zone file.
+func LoadRootZone(path string) (*RootZone, error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+
+	return ParseRootZone(f)
+}
+
+func parseSoa(fields []string) (*RdSoa, error) {
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("soa with %d fields", len(fields))
+	}
+
+	mname, e := ParseDomain(fields[0])
+	if e != nil {
+		return nil, e
+	}
+	rname, e := ParseDomain(fields[1])
+	if e != nil {
+		return nil, e
+	}
+
+	var nums [5]uint32
+	for i := range nums {
+		n, e := strconv.ParseUint(fields[2+i], 10, 32)
+		if e != nil {
+			return nil, fmt.Errorf("invalid soa field %q", fields[2+i])
+		}
+		nums[i] = uint32(n)
+	}
+
+	return &RdSoa{
+		Mname:   mname.labels,
+		Rname:   rname.labels,
+		Serial:  nums[0],
+		Refresh: nums[1],
+		Retry:   nums[2],
+		Expire:  nums[3],
+		Minimum: nums[4],
+	}, nil
+}
+
+// Serial returns the SOA serial of the zone.
+func (z *RootZone) Serial() uint32 { return z.soa.Rdata.(*RdSoa).Serial }
+
+// Expires returns when the mirror expires, which is the SOA expire
+// after it is loaded.
+func (z *RootZone) Expires() time.Time {
+	expire := z.soa.Rdata.(*RdSoa).Expire
+	return z.loaded.Add(time.Duration(expire) * time.Second)
+}
+
+// Expired checks if the mirror is expired, and should be reloaded.
+func (z *RootZone) Expired() bool { return !time.Now().Before(z.Expires()) }
+
+func (z *RootZone) find(d *Domain, t uint16) []*RR {
+	var ret []*RR
+	for _, rr := range z.records[d.key()] {
+		if rr.Type == t {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+// reply answers a question like a root server: a referral to the top
+// level domain, a name error, or the records of the root itself.
+func (z *RootZone) reply(q *Question) *Packet {
+	ret := &Packet{
+		Flag:     FlagResponse,
+		Question: q,
+	}
+
+	d := q.Domain
+	if d.IsRoot() {
+		ret.Flag |= FlagAA
+		ret.Answer = z.find(Root, q.Type)
+		if len(ret.Answer) == 0 {
+			ret.Authority = []*RR{z.soa}
+		}
+		return ret
+	}
+
+	tld := d
+	for !tld.Parent().IsRoot() {
+		tld = tld.Parent()
+	}
+	nss := z.find(tld, NS)
+	if len(nss) == 0 {
+		ret.Flag |= FlagAA | RcodeNameError
+		ret.Authority = []*RR{z.soa}
+		return ret
+	}
+
+	ret.Authority = nss
+	for _, ns := range nss {
+		server := RdToDomain(ns.Rdata)
+		ret.Addition = append(ret.Addition, z.find(server, A)...)
+		ret.Addition = append(ret.Addition, z.find(server, AAAA)...)
+	}
+	return ret
+}
@@ -0,0 +1,64 @@
+package dns8
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+const testRootZone = `
+$TTL 86400
+.	IN	SOA	a.root-servers.net. nstld.verisign-grs.com. (
+		2024010100 ; serial
+		1800 900 604800 86400 )
+.	518400	IN	NS	a.root-servers.net.
+.	518400	IN	DNSKEY	256 3 8 AwEAAa ; skipped
+com.	172800	IN	NS	a.gtld.com.
+	172800	IN	DS	19718 13 2 8acbb0cd ; skipped
+a.gtld.com.	172800	IN	A	10.0.0.1
+`
+
+func TestRootZone(t *testing.T) {
+	z, e := ParseRootZone(strings.NewReader(testRootZone))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if z.Serial() != 2024010100 || z.Expired() {
+		t.Errorf("unexpected soa, serial %d", z.Serial())
+	}
+
+	// all the root servers are dead
+	n := fakeInternet()
+	for _, s := range MakeRoots().List() {
+		n.Handle(s.IP, nil)
+	}
+	c := n.NewClient()
+	defer c.Close()
+
+	cur := testCursor(c)
+	cur.RootZone = z
+	ips := NewIPs(D("www.example.com"))
+	if _, e := cur.T(ips); e != nil {
+		t.Fatal(e)
+	}
+	if got := ips.IPs(); len(got) != 1 || !got[0].Equal(net.ParseIP("10.0.2.1")) {
+		t.Errorf("expect 10.0.2.1, got %v", got)
+	}
+
+	p := z.reply(&Question{D("none"), A, IN})
+	if p.Rcode() != RcodeNameError {
+		t.Errorf("expect name error for an unknown tld")
+	}
+
+	expired := strings.Replace(testRootZone, "604800", "0", 1)
+	if z, e = ParseRootZone(strings.NewReader(expired)); e != nil {
+		t.Fatal(e)
+	}
+	if !z.Expired() {
+		t.Error("expect the zone expired")
+	}
+
+	if _, e := ParseRootZone(strings.NewReader(". NS a.root.\n")); e == nil {
+		t.Error("expect an error for a zone without soa")
+	}
+}
@@ -2,35 +2,96 @@ package dns8
 
 import (
 	"fmt"
+	"io"
 	"net"
+	"os"
 )
 
-// MakeRoots makes the name server set for the root.
+// MakeRoots makes the name server set for the root from the built-in
+// hints.
 func MakeRoots() *ZoneServers {
 	ret := NewZoneServers(D("."))
 
-	ns := func(n, ip string) {
+	ns := func(n, ip, ip6 string) {
 		ret.Add(
 			D(fmt.Sprintf("%s.root-servers.net", n)),
-			net.ParseIP(ip),
+			net.ParseIP(ip), net.ParseIP(ip6),
 		)
 	}
 
-	// see en.wikipedia.org/wiki/Root_name_server for reference
-	// (last update: year 2012)
-	ns("a", "198.41.0.4")     // Verisign
-	ns("b", "192.228.79.201") // USC-ISI
-	ns("c", "192.33.4.12")    // Cogent
-	ns("d", "199.7.91.13")    // U Maryland
-	ns("e", "192.203.230.10") // NASA
-	ns("f", "192.5.5.241")    // Internet Systems Consortium
-	ns("g", "192.112.36.4")   // DISA
-	ns("h", "128.63.2.53")    // U.S. Army Research Lab
-	ns("i", "192.36.148.17")  // Netnod
-	ns("j", "192.58.128.30")  // Verisign
-	ns("k", "193.0.14.129")   // RIPE NCC
-	ns("l", "199.7.83.42")    // ICANN
-	ns("m", "202.12.27.33")   // WIDE Project
+	// see www.internic.net/domain/named.root for reference
+	// (last update: year 2024)
+	ns("a", "198.41.0.4", "2001:503:ba3e::2:30")   // Verisign
+	ns("b", "170.247.170.2", "2801:1b8:10::b")     // USC-ISI
+	ns("c", "192.33.4.12", "2001:500:2::c")        // Cogent
+	ns("d", "199.7.91.13", "2001:500:2d::d")       // U Maryland
+	ns("e", "192.203.230.10", "2001:500:a8::e")    // NASA
+	ns("f", "192.5.5.241", "2001:500:2f::f")       // Internet Systems Consortium
+	ns("g", "192.112.36.4", "2001:500:12::d0d")    // DISA
+	ns("h", "198.97.190.53", "2001:500:1::53")     // U.S. Army Research Lab
+	ns("i", "192.36.148.17", "2001:7fe::53")       // Netnod
+	ns("j", "192.58.128.30", "2001:503:c27::2:30") // Verisign
+	ns("k", "193.0.14.129", "2001:7fd::1")         // RIPE NCC
+	ns("l", "199.7.83.42", "2001:500:9f::42")      // ICANN
+	ns("m", "202.12.27.33", "2001:dc3::35")        // WIDE Project
 
 	return ret
 }
+
+// ParseHints parses a root hints file in the format of named.root,
+// and makes the name server set for the root.
+func ParseHints(r io.Reader) (*ZoneServers, error) {
+	var servers []*Domain
+	addrs := make(map[string][]net.IP)
+
+	e := readZone(r, func(l *zoneLine) error {
+		if len(l.rdata) != 1 {
+			return l.errorf("invalid record")
+		}
+		rdata := l.rdata[0]
+
+		switch l.typ {
+		case "ns":
+			if !l.owner.IsRoot() {
+				return nil
+			}
+			server, e := ParseDomain(rdata)
+			if e != nil {
+				return l.errorf("%v", e)
+			}
+			servers = append(servers, server)
+		case "a", "aaaa":
+			ip := net.ParseIP(rdata)
+			if ip == nil {
+				return l.errorf("invalid ip %q", rdata)
+			}
+			k := l.owner.key()
+			addrs[k] = append(addrs[k], ip)
+		}
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no root name server in hints")
+	}
+
+	ret := NewZoneServers(Root)
+	for _, server := range servers {
+		ret.Add(server, addrs[server.key()]...)
+	}
+	return ret, nil
+}
+
+// LoadHints loads a root hints file, like named.root.
+func LoadHints(path string) (*ZoneServers, error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+
+	return ParseHints(f)
+}
@@ -0,0 +1,85 @@
+package dns8
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testHints = `
+; a part of named.root
+.                        3600000      NS    A.ROOT-SERVERS.NET.
+A.ROOT-SERVERS.NET.      3600000      A     198.41.0.4
+A.ROOT-SERVERS.NET.      3600000      AAAA  2001:503:ba3e::2:30
+;
+.                        3600000      NS    B.ROOT-SERVERS.NET.
+B.ROOT-SERVERS.NET.      3600000 IN   A     170.247.170.2
+`
+
+func TestParseHints(t *testing.T) {
+	zs, e := ParseHints(strings.NewReader(testHints))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	res, unres := zs.Prepare()
+	if len(res) != 3 || len(unres) != 0 {
+		t.Fatalf("expect 3 addresses, got %d, %d", len(res), len(unres))
+	}
+
+	if _, e := ParseHints(strings.NewReader("a.root. A 1.2.3.4\n")); e == nil {
+		t.Error("expect an error for hints without ns")
+	}
+	if _, e := ParseHints(strings.NewReader(". NS\n")); e == nil {
+		t.Error("expect an error for an invalid line")
+	}
+}
+
+func TestPrime(t *testing.T) {
+	n := fakeInternet()
+	fresh := net.ParseIP("10.9.0.1")
+	root := &fakeServer{Root, []*RR{
+		rrNS(".", "x.root-servers.net"),
+		rrA("x.root-servers.net", fresh.String()),
+		rrNS("com", "a.gtld.com"),
+		rrA("a.gtld.com", "10.0.0.1"),
+	}}
+	for _, s := range MakeRoots().List() {
+		n.Handle(s.IP, root.handle)
+	}
+	n.Handle(fresh, root.handle)
+
+	c := n.NewClient()
+	defer c.Close()
+	c.MaxTimeout = time.Millisecond * 50
+
+	tm := NewTerm(c)
+	tm.Log = nil
+	tm.PrimeRoots = true
+	ips := NewIPs(D("www.example.com"))
+	if _, e := tm.T(ips); e != nil {
+		t.Fatal(e)
+	}
+	if len(ips.IPs()) != 1 {
+		t.Errorf("expect one ip, got %v", ips.IPs())
+	}
+
+	servers := tm.Roots.List()
+	if len(servers) != 1 || !servers[0].IP.Equal(fresh) {
+		t.Errorf("expect the primed root, got %v", servers)
+	}
+
+	// all the hinted roots are dead
+	dead := NewZoneServers(Root)
+	dead.Add(D("dead.root-servers.net"), net.ParseIP("10.9.0.2"))
+	tm = NewTerm(c)
+	tm.Log = nil
+	tm.Roots = dead
+	if e := tm.Prime(); e == nil {
+		t.Error("expect priming to fail")
+	}
+	if tm.Roots != dead {
+		t.Error("expect the hints kept")
+	}
+}
@@ -67,6 +67,10 @@ func unpackRR(in *bytes.Reader, p []byte) (*RR, error) {
 }
 
 func (rr *RR) String() string {
+	if rr.Type == OPT {
+		return RRToEdns(rr).String()
+	}
+
 	buf := new(bytes.Buffer)
 	fmt.Fprintf(buf, "%s %s ", rr.Domain.String(), TypeString(rr.Type))
 	if rr.Class != IN {
@@ -80,6 +84,10 @@ func (rr *RR) String() string {
 
 // Digest returns a one line digest of the rr record
 func (rr *RR) Digest() string {
+	if rr.Type == OPT {
+		return RRToEdns(rr).String()
+	}
+
 	buf := new(bytes.Buffer)
 	fmt.Fprintf(buf, "%s %s ", rr.Domain.String(), TypeString(rr.Type))
 	if rr.Class != IN {
@@ -0,0 +1,412 @@
+package dns8
+
+import (
+	"container/list"
+	"net"
+	"sync"
+	"time"
+)
+
+// Cache limits
+const (
+	maxNegativeTTL = 3 * time.Hour // RFC 2308 suggests 1 to 3 hours
+	maxCnameChain  = 8
+)
+
+// rrKey is the key of a record set. Type 0 is for a name error,
+// which applies to all the types of a name.
+type rrKey struct {
+	domain string
+	typ    uint16
+	class  uint16
+}
+
+func keyOfRR(d *Domain, t, class uint16) rrKey {
+	return rrKey{d.key(), t, class}
+}
+
+type rrEntry struct {
+	key      rrKey
+	rrs      []*RR // nil for a negative entry
+	soa      *RR   // for a negative entry
+	expires  time.Time
+	referral bool // from a referral, only used for delegations
+}
+
+// aged returns copies of the records with the TTLs that remain.
+func aged(rrs []*RR, expires, now time.Time) []*RR {
+	ttl := uint32(expires.Sub(now) / time.Second)
+	ret := make([]*RR, len(rrs))
+	for i, rr := range rrs {
+		cp := *rr
+		if cp.TTL > ttl {
+			cp.TTL = ttl
+		}
+		ret[i] = &cp
+	}
+	return ret
+}
+
+// DefaultRRCacheSize is the default max number of record sets in a
+// record cache.
+const DefaultRRCacheSize = 100000
+
+// RRCache is a record cache keyed by name, type and class. Records
+// expire by their TTLs, and name errors and empty answers are cached
+// negatively as in RFC 2308, using the SOA minimum. The name servers
+// and the glues of referrals are only used for delegations, and never
+// answer queries. When the cache is full, the least recently used
+// record sets are evicted first. It is safe for concurrent use.
+type RRCache struct {
+	lock    sync.Mutex
+	size    int
+	entries map[rrKey]*list.Element
+	lru     *list.List // of *rrEntry, most recently used first
+}
+
+// NewRRCache creates an empty record cache with the default size.
+func NewRRCache() *RRCache {
+	return NewRRCacheSize(DefaultRRCacheSize)
+}
+
+// NewRRCacheSize creates an empty record cache that holds at most
+// size record sets, 0 for no limit.
+func NewRRCacheSize(size int) *RRCache {
+	ret := new(RRCache)
+	ret.size = size
+	ret.entries = make(map[rrKey]*list.Element)
+	ret.lru = list.New()
+	return ret
+}
+
+func (c *RRCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*rrEntry)
+	delete(c.entries, entry.key)
+}
+
+// set puts an entry, and evicts the least recently used ones when the
+// cache is full. A referral never replaces an authoritative entry that
+// is alive, as the ranking in RFC 2181 section 5.4.1.
+func (c *RRCache) set(entry *rrEntry, now time.Time) {
+	if elem := c.entries[entry.key]; elem != nil {
+		old := elem.Value.(*rrEntry)
+		if entry.referral && !old.referral && now.Before(old.expires) {
+			return
+		}
+		c.remove(elem)
+	}
+
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	for c.size > 0 && c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Put caches the records, grouped into record sets. A record set
+// expires with its smallest TTL.
+func (c *RRCache) Put(rrs []*RR) {
+	c.put(rrs, false)
+}
+
+func (c *RRCache) put(rrs []*RR, referral bool) {
+	sets := make(map[rrKey][]*RR)
+	var keys []rrKey
+	for _, rr := range rrs {
+		if rr.Type == OPT {
+			continue
+		}
+		k := keyOfRR(rr.Domain, rr.Type, rr.Class)
+		if sets[k] == nil {
+			keys = append(keys, k)
+		}
+		sets[k] = append(sets[k], rr)
+	}
+
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, k := range keys {
+		set := sets[k]
+		ttl := set[0].TTL
+		for _, rr := range set {
+			if rr.TTL < ttl {
+				ttl = rr.TTL
+			}
+		}
+		if ttl == 0 {
+			continue
+		}
+
+		c.set(&rrEntry{
+			key:      k,
+			rrs:      set,
+			expires:  now.Add(time.Duration(ttl) * time.Second),
+			referral: referral,
+		}, now)
+	}
+}
+
+// PutNegative caches that the domain has no record of type t, with
+// the SOA record in the authority section of the reply. Type 0 is for
+// a name error. The entry expires with the SOA TTL or the SOA minimum,
+// whichever is smaller.
+func (c *RRCache) PutNegative(d *Domain, t uint16, soa *RR) {
+	rd, ok := soa.Rdata.(*RdSoa)
+	if !ok {
+		return
+	}
+
+	ttl := time.Duration(soa.TTL) * time.Second
+	if min := time.Duration(rd.Minimum) * time.Second; min < ttl {
+		ttl = min
+	}
+	if ttl > maxNegativeTTL {
+		ttl = maxNegativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.set(&rrEntry{
+		key:     keyOfRR(d, t, IN),
+		soa:     soa,
+		expires: now.Add(ttl),
+	}, now)
+}
+
+// lookup returns the entry of the records, nil if none or expired.
+// Referral entries are only returned when referral is true.
+func (c *RRCache) lookup(d *Domain, t uint16, now time.Time,
+	referral bool,
+) *rrEntry {
+	elem := c.entries[keyOfRR(d, t, IN)]
+	if elem == nil {
+		return nil
+	}
+	entry := elem.Value.(*rrEntry)
+	if !now.Before(entry.expires) {
+		c.remove(elem)
+		return nil
+	}
+	if entry.referral && !referral {
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return entry
+}
+
+func (c *RRCache) get(d *Domain, t uint16, now time.Time) *rrEntry {
+	return c.lookup(d, t, now, false)
+}
+
+// records returns the cached records with the TTLs that remain.
+func (c *RRCache) records(d *Domain, t uint16, referral bool) []*RR {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	entry := c.lookup(d, t, now, referral)
+	if entry == nil || entry.rrs == nil {
+		return nil
+	}
+	return aged(entry.rrs, entry.expires, now)
+}
+
+// Get returns the cached records of type t for the domain, with the
+// TTLs that remain. It returns nil if not cached, or cached negatively.
+func (c *RRCache) Get(d *Domain, t uint16) []*RR {
+	return c.records(d, t, false)
+}
+
+// Clean removes the expired entries.
+func (c *RRCache) Clean() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if !now.Before(elem.Value.(*rrEntry).expires) {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// Len returns the number of cached entries, including the expired
+// ones that are not cleaned yet.
+func (c *RRCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Len()
+}
+
+// reply makes a reply from the cache for the question, following the
+// cached cnames. It returns nil if the cache cannot answer.
+func (c *RRCache) reply(d *Domain, t uint16) *Packet {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	ret := &Packet{
+		Flag:     FlagResponse | FlagAA,
+		Question: &Question{d, t, IN},
+	}
+
+	if entry := c.get(d, 0, now); entry != nil {
+		ret.Flag |= RcodeNameError
+		ret.Authority = aged([]*RR{entry.soa}, entry.expires, now)
+		return ret
+	}
+	if entry := c.get(d, t, now); entry != nil && entry.rrs == nil {
+		ret.Authority = aged([]*RR{entry.soa}, entry.expires, now)
+		return ret
+	}
+
+	name := d
+	for i := 0; i < maxCnameChain; i++ {
+		if entry := c.get(name, t, now); entry != nil && entry.rrs != nil {
+			ret.Answer = append(ret.Answer,
+				aged(entry.rrs, entry.expires, now)...)
+			return ret
+		}
+		if t == CNAME {
+			break
+		}
+
+		entry := c.get(name, CNAME, now)
+		if entry == nil || entry.rrs == nil {
+			break
+		}
+		ret.Answer = append(ret.Answer,
+			aged(entry.rrs, entry.expires, now)...)
+		name = RdToDomain(entry.rrs[0].Rdata)
+	}
+
+	// a cname to chase is only an answer for addresses
+	if len(ret.Answer) > 0 && (t == A || t == AAAA) {
+		return ret
+	}
+	return nil
+}
+
+// chainEnd follows the cnames in the answer of packet p from domain
+// d, and returns the last name of the chain.
+func chainEnd(p *Packet, d *Domain) *Domain {
+	for i := 0; i < maxCnameChain; i++ {
+		next := cnameOf(p.Answer, d)
+		if next == nil {
+			break
+		}
+		d = next
+	}
+	return d
+}
+
+func cnameOf(rrs []*RR, d *Domain) *Domain {
+	for _, rr := range rrs {
+		if rr.Type == CNAME && rr.Domain.Equal(d) {
+			return RdToDomain(rr.Rdata)
+		}
+	}
+	return nil
+}
+
+// putReply caches the records of a reply from a server of the zone.
+// Records out of the zone are not trusted, and hence not cached.
+func (c *RRCache) putReply(zone *Domain, q *Question, p *Packet) {
+	rcode := p.Rcode()
+	if rcode != RcodeOkay && rcode != RcodeNameError {
+		return
+	}
+
+	var soa *RR
+	for _, rr := range p.Authority {
+		if rr.Type == SOA && zone.IsZoneOf(rr.Domain) {
+			soa = rr
+			break
+		}
+	}
+
+	var answers []*RR
+	for _, rr := range p.Answer {
+		if zone.IsZoneOf(rr.Domain) {
+			answers = append(answers, rr)
+		}
+	}
+	c.Put(answers)
+
+	end := q.Domain
+	if q.Type != CNAME {
+		end = chainEnd(p, q.Domain)
+	}
+
+	if rcode == RcodeNameError {
+		// the name error is for the last name of the cname chain
+		if soa != nil && zone.IsZoneOf(end) {
+			c.PutNegative(end, 0, soa)
+		}
+		return
+	}
+
+	if !end.Equal(q.Domain) {
+		return // only the cnames are cached
+	}
+	if len(p.SelectAnswers(q.Domain, q.Type)) > 0 {
+		return
+	}
+
+	redirects := p.SelectRedirects(zone, q.Domain)
+	if len(redirects) == 0 {
+		if soa != nil {
+			c.PutNegative(q.Domain, q.Type, soa)
+		}
+		return
+	}
+
+	// a delegation, with the glued addresses in the zone
+	c.put(redirects, true)
+	for _, ns := range redirects {
+		var glue []*RR
+		for _, rr := range p.SelectAddrs(RdToDomain(ns.Rdata)) {
+			if zone.IsZoneOf(rr.Domain) {
+				glue = append(glue, rr)
+			}
+		}
+		c.put(glue, true)
+	}
+}
+
+// Delegation returns the name servers of the closest zone of the
+// domain that are cached, nil if none.
+func (c *RRCache) Delegation(d *Domain) *ZoneServers {
+	for zone := d; !zone.IsRoot(); zone = zone.Parent() {
+		nss := c.records(zone, NS, true)
+		if len(nss) == 0 {
+			continue
+		}
+
+		ret := NewZoneServers(zone)
+		ret.AddRecords(nss)
+		for _, ns := range nss {
+			server := RdToDomain(ns.Rdata)
+			addrs := append(c.records(server, A, true),
+				c.records(server, AAAA, true)...)
+			ret.AddRecords(addrs)
+
+			ips := make([]net.IP, 0, len(addrs))
+			for _, rr := range addrs {
+				ips = append(ips, RdToIP(rr.Rdata))
+			}
+			ret.Add(server, ips...)
+		}
+		return ret
+	}
+
+	return nil
+}
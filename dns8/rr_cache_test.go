@@ -0,0 +1,102 @@
+package dns8
+
+import (
+	"testing"
+)
+
+func TestRRCacheReferral(t *testing.T) {
+	cache := NewRRCache()
+	q := &Question{D("www.example.com"), A, IN}
+	p := &Packet{
+		Flag:      FlagResponse,
+		Question:  q,
+		Authority: Section{rrNS("example.com", "ns1.example.com")},
+		Addition:  Section{rrA("ns1.example.com", "10.0.1.1")},
+	}
+	cache.putReply(D("com"), q, p)
+
+	if rrs := cache.Get(D("example.com"), NS); rrs != nil {
+		t.Errorf("referral should not answer, got %v", rrs)
+	}
+	if rrs := cache.Get(D("ns1.example.com"), A); rrs != nil {
+		t.Errorf("glue should not answer, got %v", rrs)
+	}
+	if cache.reply(D("example.com"), NS) != nil {
+		t.Error("referral should not make a reply")
+	}
+
+	zs := cache.Delegation(D("www.example.com"))
+	if zs == nil || !zs.Zone().Equal(D("example.com")) {
+		t.Fatalf("expect the delegation of example.com, got %v", zs)
+	}
+	if res, _ := zs.Prepare(); len(res) != 1 {
+		t.Errorf("expect the glued server, got %v", res)
+	}
+
+	// authoritative records replace the referral, but not the reverse
+	cache.Put([]*RR{rrNS("example.com", "ns2.example.com")})
+	cache.putReply(D("com"), q, p)
+	rrs := cache.Get(D("example.com"), NS)
+	if len(rrs) != 1 || !RdToDomain(rrs[0].Rdata).Equal(D("ns2.example.com")) {
+		t.Errorf("expect the authoritative ns, got %v", rrs)
+	}
+}
+
+func TestRRCacheCname(t *testing.T) {
+	cache := NewRRCache()
+	zone := D("example.com")
+	soa := rrSOA("example.com", 300)
+
+	// a name error is for the end of the cname chain
+	q := &Question{D("www.example.com"), A, IN}
+	cache.putReply(zone, q, &Packet{
+		Flag:      FlagResponse | RcodeNameError,
+		Question:  q,
+		Answer:    Section{rrCNAME("www.example.com", "gone.example.com")},
+		Authority: Section{soa},
+	})
+	if rrs := cache.Get(D("www.example.com"), CNAME); len(rrs) != 1 {
+		t.Errorf("expect the cname cached, got %v", rrs)
+	}
+	if p := cache.reply(D("gone.example.com"), A); p == nil ||
+		p.Rcode() != RcodeNameError {
+		t.Errorf("expect the name error of the target, got %v", p)
+	}
+	if p := cache.reply(D("www.example.com"), TXT); p != nil &&
+		p.Rcode() == RcodeNameError {
+		t.Error("the cname owner should not be a name error")
+	}
+
+	// a cname for other types than addresses is cached alone
+	q = &Question{D("mail.example.com"), MX, IN}
+	cache.putReply(zone, q, &Packet{
+		Flag:      FlagResponse,
+		Question:  q,
+		Answer:    Section{rrCNAME("mail.example.com", "mx.example.net")},
+		Authority: Section{soa},
+	})
+	if p := cache.reply(D("mail.example.com"), MX); p != nil {
+		t.Errorf("expect no cached answer for mx, got %v", p)
+	}
+	if rrs := cache.Get(D("mail.example.com"), CNAME); len(rrs) != 1 {
+		t.Errorf("expect the cname cached, got %v", rrs)
+	}
+}
+
+func TestRRCacheSize(t *testing.T) {
+	cache := NewRRCacheSize(2)
+	cache.Put([]*RR{rrA("a.example.com", "10.0.0.1")})
+	cache.Put([]*RR{rrA("b.example.com", "10.0.0.2")})
+	cache.Get(D("a.example.com"), A) // a is used more recently
+	cache.Put([]*RR{rrA("c.example.com", "10.0.0.3")})
+
+	if n := cache.Len(); n != 2 {
+		t.Errorf("expect 2 entries, got %d", n)
+	}
+	if cache.Get(D("b.example.com"), A) != nil {
+		t.Error("expect b evicted")
+	}
+	if cache.Get(D("a.example.com"), A) == nil {
+		t.Error("expect a kept")
+	}
+}
@@ -0,0 +1,127 @@
+package dns8
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Default bounds of the query timeout
+const (
+	DefaultMinTimeout = time.Millisecond * 200
+	DefaultMaxTimeout = time.Second * 3
+)
+
+// RTTStat is the measured round trip time statistics of a server
+type RTTStat struct {
+	SRTT     time.Duration // smoothed round trip time
+	RTTVar   time.Duration // round trip time variance
+	Samples  int
+	Timeouts int // total number of timeouts
+	Backoff  int // number of timeouts since the last reply
+}
+
+// Timeout returns the query timeout derived from the statistics,
+// bounded by min and max.
+func (s *RTTStat) Timeout(min, max time.Duration) time.Duration {
+	if s == nil || s.Samples == 0 {
+		return max
+	}
+
+	ret := s.SRTT + 4*s.RTTVar
+	for i := 0; i < s.Backoff && ret < max; i++ {
+		ret *= 2
+	}
+
+	if ret < min {
+		return min
+	}
+	if ret > max {
+		return max
+	}
+	return ret
+}
+
+// rttTable keeps the round trip time statistics of each server ip,
+// in the fashion of the TCP retransmission timer (RFC 6298).
+type rttTable struct {
+	lock  sync.Mutex
+	stats map[ipKey]*RTTStat
+}
+
+func newRTTTable() *rttTable {
+	ret := new(rttTable)
+	ret.stats = make(map[ipKey]*RTTStat)
+	return ret
+}
+
+func (t *rttTable) stat(ip net.IP) *RTTStat {
+	k := keyOfIP(ip)
+	ret := t.stats[k]
+	if ret == nil {
+		ret = new(RTTStat)
+		t.stats[k] = ret
+	}
+	return ret
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func (t *rttTable) sample(ip net.IP, d time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := t.stat(ip)
+	if s.Samples == 0 {
+		s.SRTT = d
+		s.RTTVar = d / 2
+	} else {
+		s.RTTVar = (3*s.RTTVar + absDuration(s.SRTT-d)) / 4
+		s.SRTT = (7*s.SRTT + d) / 8
+	}
+	s.Samples++
+	s.Backoff = 0
+}
+
+func (t *rttTable) timeout(ip net.IP) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := t.stat(ip)
+	s.Timeouts++
+	s.Backoff++
+}
+
+func (t *rttTable) get(ip net.IP) (RTTStat, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := t.stats[keyOfIP(ip)]
+	if s == nil {
+		return RTTStat{}, false
+	}
+	return *s, true
+}
+
+func (t *rttTable) deadline(ip net.IP, min, max time.Duration) time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.stats[keyOfIP(ip)].Timeout(min, max)
+}
+
+func (t *rttTable) all() map[string]RTTStat {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	ret := make(map[string]RTTStat)
+	for k, s := range t.stats {
+		ret[net.IP(k[:]).String()] = *s
+	}
+	return ret
+}
@@ -0,0 +1,54 @@
+package dns8
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRTTDeadline(t *testing.T) {
+	const (
+		ms   = time.Millisecond
+		lost = -1 // a timeout instead of a sample
+	)
+
+	for _, test := range []struct {
+		name     string
+		events   []time.Duration
+		min, max time.Duration
+		expect   time.Duration
+	}{
+		{"unknown", nil, 200 * ms, 3 * time.Second, 3 * time.Second},
+		{"only timeouts", []time.Duration{lost, lost},
+			200 * ms, 3 * time.Second, 3 * time.Second},
+		{"one sample", []time.Duration{100 * ms},
+			200 * ms, 3 * time.Second, 300 * ms},
+		{"two samples", []time.Duration{100 * ms, 100 * ms},
+			200 * ms, 3 * time.Second, 250 * ms},
+		{"min bound", []time.Duration{10 * ms},
+			200 * ms, 3 * time.Second, 200 * ms},
+		{"max bound", []time.Duration{2 * time.Second},
+			200 * ms, 3 * time.Second, 3 * time.Second},
+		{"backoff", []time.Duration{100 * ms, lost},
+			200 * ms, 3 * time.Second, 600 * ms},
+		{"backoff to max", []time.Duration{100 * ms, lost, lost, lost, lost},
+			200 * ms, 3 * time.Second, 3 * time.Second},
+		{"backoff reset", []time.Duration{100 * ms, lost, 100 * ms},
+			200 * ms, 3 * time.Second, 250 * ms},
+	} {
+		rtts := newRTTTable()
+		ip := net.ParseIP("10.0.0.1")
+		for _, d := range test.events {
+			if d == lost {
+				rtts.timeout(ip)
+			} else {
+				rtts.sample(ip, d)
+			}
+		}
+
+		got := rtts.deadline(ip, test.min, test.max)
+		if got != test.expect {
+			t.Errorf("%s: expect %v, got %v", test.name, test.expect, got)
+		}
+	}
+}
@@ -33,6 +33,13 @@ func (s Section) unpack(in *bytes.Reader, p []byte) error {
 	return nil
 }
 
+// pack packs the entire section
+func (s Section) pack(out *bytes.Buffer) {
+	for _, rr := range s {
+		rr.pack(out)
+	}
+}
+
 // PrintTo prints the section to a printer.
 func (s Section) PrintTo(p *Printer) {
 	for _, rr := range s {
@@ -0,0 +1,11 @@
+package dns8
+
+// SelectAddr selects A and AAAA records for a particular domain
+type SelectAddr struct{ Domain *Domain }
+
+// Select checks if the records is an A or AAAA record for the domain.
+func (s *SelectAddr) Select(rr *RR, _ int) bool {
+	return (rr.Type == A || rr.Type == AAAA) && rr.Domain.Equal(s.Domain)
+}
+
+var _ Selector = new(SelectAddr)
@@ -11,7 +11,10 @@ func (s *SelectAnswer) Select(rr *RR, _ int) bool {
 	if !rr.Domain.Equal(s.Domain) {
 		return false
 	}
-	return s.Type == rr.Type || (s.Type == A && rr.Type == CNAME)
+	if s.Type == rr.Type {
+		return true
+	}
+	return (s.Type == A || s.Type == AAAA) && rr.Type == CNAME
 }
 
 var _ Selector = new(SelectAnswer)
@@ -0,0 +1,15 @@
+package dns8
+
+// SelectDenial selects the NSEC and NSEC3 records of a zone in the
+// authority section, which prove the absence of names or types.
+type SelectDenial struct{ Zone *Domain }
+
+// Select checks if the record is a denial of existence record.
+func (s *SelectDenial) Select(rr *RR, section int) bool {
+	if section != SecAuth || !s.Zone.IsZoneOf(rr.Domain) {
+		return false
+	}
+	return rr.Type == NSEC || rr.Type == NSEC3
+}
+
+var _ Selector = new(SelectDenial)
@@ -0,0 +1,18 @@
+package dns8
+
+// SelectSig selects the RRSIG records of a domain that cover a type.
+type SelectSig struct {
+	Domain  *Domain
+	Covered uint16
+}
+
+// Select checks if the record is a signature over the record set.
+func (s *SelectSig) Select(rr *RR, _ int) bool {
+	if rr.Type != RRSIG || !rr.Domain.Equal(s.Domain) {
+		return false
+	}
+	sig, ok := rr.Rdata.(*RdRrsig)
+	return ok && sig.TypeCovered == s.Covered
+}
+
+var _ Selector = new(SelectSig)
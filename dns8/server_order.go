@@ -0,0 +1,98 @@
+package dns8
+
+import (
+	"sort"
+	"time"
+)
+
+// ServerOrder is a strategy that orders the resolved name servers
+// of a zone before querying them.
+type ServerOrder interface {
+	Order(servers []*NameServer) []*NameServer
+}
+
+// RandomOrder shuffles the servers.
+type RandomOrder struct{}
+
+var _ ServerOrder = RandomOrder{}
+
+// Order returns the servers in a random order.
+func (RandomOrder) Order(servers []*NameServer) []*NameServer {
+	return shuffleList(servers)
+}
+
+// DefaultExplore is the default chance of exploring a random server.
+const DefaultExplore = 0.1
+
+// FastOrder prefers healthy servers with low observed latency.
+// Servers that are never queried are tried first so that they get
+// measured, and servers that failed recently are tried last.
+// With a chance of Explore, a random healthy server is tried first.
+type FastOrder struct {
+	Client  *Client
+	Explore float64
+}
+
+var _ ServerOrder = new(FastOrder)
+
+// NewFastOrder creates a server order strategy that uses the round
+// trip time and health statistics of the client.
+func NewFastOrder(c *Client) *FastOrder {
+	return &FastOrder{
+		Client:  c,
+		Explore: DefaultExplore,
+	}
+}
+
+type rankedServer struct {
+	*NameServer
+	srtt  time.Duration
+	score float64
+}
+
+// Order returns the servers, fast and healthy ones first.
+func (o *FastOrder) Order(servers []*NameServer) []*NameServer {
+	servers = shuffleList(servers) // randomize the ties
+	healthy := make([]*rankedServer, 0, len(servers))
+	sick := make([]*rankedServer, 0, len(servers))
+
+	for _, s := range servers {
+		r := &rankedServer{NameServer: s}
+		if stat, found := o.Client.RTT(s.IP); found {
+			r.srtt = stat.SRTT
+		}
+		r.score = o.Client.Health.Score(s.IP)
+		if r.score < 0.5 {
+			healthy = append(healthy, r)
+		} else {
+			sick = append(sick, r)
+		}
+	}
+
+	sort.SliceStable(healthy, func(i, j int) bool {
+		return healthy[i].srtt < healthy[j].srtt
+	})
+	sort.SliceStable(sick, func(i, j int) bool {
+		return sick[i].score < sick[j].score
+	})
+
+	if n := len(healthy); n > 1 {
+		randLock.Lock()
+		explore := random.Float64() < o.Explore
+		pick := random.Intn(n)
+		randLock.Unlock()
+
+		if explore {
+			healthy[0], healthy[pick] = healthy[pick], healthy[0]
+		}
+	}
+
+	ret := make([]*NameServer, 0, len(servers))
+	for _, r := range healthy {
+		ret = append(ret, r.NameServer)
+	}
+	for _, r := range sick {
+		ret = append(ret, r.NameServer)
+	}
+	return ret
+}
@@ -0,0 +1,105 @@
+package dns8
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	ip := net.ParseIP("10.0.0.1")
+
+	for _, test := range []struct {
+		name    string
+		events  string // f for a failure, s for a reply, h for half a half life
+		healthy bool
+	}{
+		{"never failed", "", true},
+		{"failed", "f", false},
+		{"failed and replied", "fs", true},
+		{"failed twice", "ff", false},
+		{"failed half a half life ago", "fh", false},
+		{"failed 1.5 half lives ago", "fhhh", true},
+		{"failed twice a half life ago", "ffhh", false},
+		{"failed twice 2.5 half lives ago", "ffhhhhh", true},
+	} {
+		h := NewHealth()
+		for _, ev := range test.events {
+			switch ev {
+			case 'f':
+				h.Fail(ip)
+			case 's':
+				h.Succeed(ip)
+			case 'h':
+				if s := h.scores[keyOfIP(ip)]; s != nil {
+					s.t = s.t.Add(-h.HalfLife / 2)
+				}
+			}
+		}
+
+		if h.Healthy(ip) != test.healthy {
+			t.Errorf("%s: expect healthy %v, got score %.2f",
+				test.name, test.healthy, h.Score(ip))
+		}
+	}
+}
+
+func TestFastOrder(t *testing.T) {
+	c, e := NewClient()
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer c.Close()
+
+	var servers []*NameServer
+	for _, s := range []struct {
+		name  string
+		rtt   time.Duration // 0 for never measured
+		fails int
+	}{
+		{"slow", 50 * time.Millisecond, 0},
+		{"fast", 10 * time.Millisecond, 0},
+		{"new", 0, 0},
+		{"failed", 5 * time.Millisecond, 1},
+		{"down", time.Millisecond, 2},
+	} {
+		ip := net.IPv4(10, 0, 0, byte(len(servers)+1))
+		servers = append(servers, &NameServer{
+			Zone:   D("example.com"),
+			Domain: D(s.name + ".example.com"),
+			IP:     ip,
+		})
+		if s.rtt > 0 {
+			c.rtts.sample(ip, s.rtt)
+		}
+		for i := 0; i < s.fails; i++ {
+			c.Health.Fail(ip)
+		}
+	}
+
+	order := NewFastOrder(c)
+	order.Explore = 0
+	const expect = "new fast slow failed down"
+	for i := 0; i < 10; i++ { // the input is shuffled
+		var names []string
+		for _, s := range order.Order(servers) {
+			names = append(names, strings.TrimSuffix(s.Domain.String(), ".example.com"))
+		}
+		if got := strings.Join(names, " "); got != expect {
+			t.Fatalf("expect %q, got %q", expect, got)
+		}
+	}
+
+	// exploring only picks from the healthy servers
+	order.Explore = 1
+	for i := 0; i < 20; i++ {
+		ordered := order.Order(servers)
+		if !c.Health.Healthy(ordered[0].IP) {
+			t.Fatalf("explored an unhealthy server %v", ordered[0])
+		}
+		if n := len(ordered); !ordered[n-1].Domain.Equal(D("down.example.com")) {
+			t.Fatalf("expect the down server last, got %v", ordered[n-1])
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package dns8
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// exchangeTCP sends the packet bytes to the server over TCP using the
+// two-byte length framing, and returns the reply bytes. It gives up
+// with the error of ctx when ctx is done.
+func exchangeTCP(ctx context.Context, addr *net.UDPAddr, p []byte) ([]byte, error) {
+	if len(p) > 0xffff {
+		return nil, errors.New("packet too long for tcp")
+	}
+
+	taddr := &net.TCPAddr{IP: addr.IP, Port: addr.Port, Zone: addr.Zone}
+	var d net.Dialer
+	conn, e := d.DialContext(ctx, "tcp", taddr.String())
+	if e != nil {
+		return nil, e
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if e := conn.SetDeadline(deadline); e != nil {
+			return nil, e
+		}
+	}
+
+	// unblocks the reads and writes when ctx is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+
+	ret, e := tcpRoundTrip(conn, p)
+	if e != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return ret, e
+}
+
+func tcpRoundTrip(conn net.Conn, p []byte) ([]byte, error) {
+	buf := make([]byte, 2+len(p))
+	enc.PutUint16(buf[0:2], uint16(len(p)))
+	copy(buf[2:], p)
+	if _, e := conn.Write(buf); e != nil {
+		return nil, e
+	}
+
+	n := make([]byte, 2)
+	if _, e := io.ReadFull(conn, n); e != nil {
+		return nil, e
+	}
+
+	buf = make([]byte, enc.Uint16(n))
+	if _, e := io.ReadFull(conn, buf); e != nil {
+		return nil, e
+	}
+
+	return buf, nil
+}
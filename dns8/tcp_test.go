@@ -0,0 +1,106 @@
+package dns8
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// tcpServer serves each tcp connection with h on the loopback, and
+// returns its address.
+func tcpServer(t *testing.T, h func(conn net.Conn)) *net.UDPAddr {
+	l, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Skip(e)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, e := l.Accept()
+			if e != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				h(conn)
+			}()
+		}
+	}()
+
+	addr := l.Addr().(*net.TCPAddr)
+	return &net.UDPAddr{IP: addr.IP, Port: addr.Port}
+}
+
+func readFramed(conn net.Conn) []byte {
+	n := make([]byte, 2)
+	if _, e := io.ReadFull(conn, n); e != nil {
+		return nil
+	}
+	ret := make([]byte, enc.Uint16(n))
+	if _, e := io.ReadFull(conn, ret); e != nil {
+		return nil
+	}
+	return ret
+}
+
+func TestExchangeTCP(t *testing.T) {
+	query := QpackID(D("lonnie.io"), A, 1234).Bytes
+	hang := func(conn net.Conn) {
+		readFramed(conn)
+		io.Copy(io.Discard, conn) // until the client closes
+	}
+
+	for _, test := range []struct {
+		name    string
+		h       func(conn net.Conn)
+		cancel  bool // cancels instead of timing out
+		reply   []byte
+		timeout bool
+		err     error
+	}{
+		{name: "reply", h: func(conn net.Conn) {
+			p := readFramed(conn)
+			conn.Write(append([]byte{0, byte(len(p))}, p...))
+		}, reply: query},
+		{name: "closed", h: func(conn net.Conn) {
+			readFramed(conn)
+		}, err: io.EOF},
+		{name: "short", h: func(conn net.Conn) {
+			readFramed(conn)
+			conn.Write([]byte{0, 10, 1, 2, 3})
+		}, err: io.ErrUnexpectedEOF},
+		{name: "timeout", h: hang, timeout: true},
+		{name: "canceled", h: hang, cancel: true, err: context.Canceled},
+	} {
+		addr := tcpServer(t, test.h)
+
+		timeout := 5 * time.Second
+		if test.timeout {
+			timeout = 50 * time.Millisecond
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		if test.cancel {
+			ctx, cancel = context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+		}
+
+		reply, e := exchangeTCP(ctx, addr, query)
+		cancel()
+
+		if test.timeout {
+			if ne, ok := e.(net.Error); !ok || !ne.Timeout() {
+				t.Errorf("%s: expect a timeout, got %v", test.name, e)
+			}
+		} else if e != test.err {
+			t.Errorf("%s: expect error %v, got %v", test.name, test.err, e)
+		}
+		if !bytes.Equal(reply, test.reply) {
+			t.Errorf("%s: expect reply %q, got %q",
+				test.name, test.reply, reply)
+		}
+	}
+}
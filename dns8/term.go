@@ -1,14 +1,18 @@
 package dns8
 
 import (
+	"context"
 	"os"
+	"time"
 )
 
 // Term is a query terminal that uses a client
 // and builds query trees.
 type Term struct {
-	client *Client
-	done   int
+	client  *Client
+	done    int
+	primed  bool
+	cleaned time.Time // when the caches are cleaned last
 
 	*TermConfig
 }
@@ -20,14 +24,29 @@ func NewTerm(c *Client) *Term {
 
 	ret.TermConfig = new(TermConfig)
 	ret.PrintFlag = PrintReply
-	ret.Retry = 3
+	ret.Retry = DefaultRetryPolicy()
+	ret.ServerOrder = NewFastOrder(c)
+	ret.Cache = NewCache()
 
 	return ret
 }
 
 // T builds a query tree in the terminal
 func (tm *Term) T(t Task) (*Branch, error) {
-	ret, e := newCursor(tm.TermConfig, tm.client).T(t)
+	return tm.TContext(context.Background(), t)
+}
+
+// TContext builds a query tree in the terminal. When ctx is canceled,
+// the outstanding queries are aborted, and the tree stops growing with
+// ErrCanceled. When a budget is exhausted, the tree stops growing with
+// the budget error. In both cases, the partial tree is returned.
+func (tm *Term) TContext(ctx context.Context, t Task) (*Branch, error) {
+	tm.startup(ctx)
+	tm.clean()
+	cur := newCursorContext(ctx, tm.TermConfig, tm.client)
+	defer cur.close()
+
+	ret, e := cur.T(t)
 	tm.done++
 
 	if e == nil {
@@ -40,12 +59,54 @@ func (tm *Term) T(t Task) (*Branch, error) {
 
 // Q builds a query tree leaf in the terminal
 func (tm *Term) Q(q *Query) (*Leaf, error) {
-	ret, e := newCursor(tm.TermConfig, tm.client).Q(q)
+	return tm.QContext(context.Background(), q)
+}
+
+// QContext builds a query tree leaf in the terminal, which is aborted
+// with ErrCanceled when ctx is canceled.
+func (tm *Term) QContext(ctx context.Context, q *Query) (*Leaf, error) {
+	tm.startup(ctx)
+	tm.clean()
+	cur := newCursorContext(ctx, tm.TermConfig, tm.client)
+	defer cur.close()
+
+	ret, e := cur.Q(q)
 	tm.done++
 
 	return ret, e
 }
 
+// startup primes the roots before the first task when configured.
+// A failed priming falls back to the hints.
+func (tm *Term) startup(ctx context.Context) {
+	if !tm.PrimeRoots || tm.primed {
+		return
+	}
+	tm.primed = true
+	tm.PrimeContext(ctx)
+}
+
+// cleanInterval is how often a term removes the expired entries from
+// its caches.
+const cleanInterval = 5 * time.Minute
+
+// clean removes the expired entries from the caches, at most once
+// every cleanInterval.
+func (tm *Term) clean() {
+	now := time.Now()
+	if now.Sub(tm.cleaned) < cleanInterval {
+		return
+	}
+	tm.cleaned = now
+
+	if c, ok := tm.Cache.(interface{ Clean() }); ok {
+		c.Clean()
+	}
+	if tm.RRCache != nil {
+		tm.RRCache.Clean()
+	}
+}
+
 // Count returns the number of queries done.
 func (tm *Term) Count() int { return tm.done }
 
@@ -9,5 +9,42 @@ type TermConfig struct {
 	Log       io.Writer
 	Out       io.Writer
 	PrintFlag int
-	Retry     int
+	Retry     RetryPolicy
+	Edns      *Edns // EDNS0 setting for outgoing queries, nil to disable
+	IPPolicy  int   // IPv4Only, IPv6Only or IPDual for reaching servers
+	Use0x20   bool  // randomizes the letter cases of query names
+
+	ServerOrder ServerOrder // orders resolved servers, nil for random
+
+	// Roots are the root name servers to start with, nil for the
+	// built-in hints. Prime sets the roots from the live answer.
+	Roots *ZoneServers
+
+	// PrimeRoots primes the roots at the first task of the term.
+	PrimeRoots bool
+
+	// RootZone answers the queries to the root servers locally, nil
+	// to always query the root servers. An expired mirror is skipped.
+	RootZone *RootZone
+
+	// Cache caches the name servers of the zones across tasks, nil
+	// to disable. Terms that share a cache share the delegations.
+	Cache ZoneCache
+
+	// RRCache caches the records and the negative answers of the
+	// recursions, nil to disable. It can be shared by terms.
+	RRCache *RRCache
+
+	// Anchors are the DS records of the root that DNSSEC validation
+	// trusts, nil for the RootAnchors.
+	Anchors []*RdDs
+
+	Budget // limits of each top-level task
+}
+
+func (cfg *TermConfig) roots() *ZoneServers {
+	if cfg.Roots != nil {
+		return cfg.Roots
+	}
+	return roots
 }
@@ -0,0 +1,143 @@
+package dns8
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// Transport carries DNS packets for a client.
+type Transport interface {
+	// Send sends a packet to the server as a datagram.
+	Send(p []byte, addr *net.UDPAddr) error
+
+	// Recv blocks until a datagram arrives. It returns ErrClosed
+	// after the transport is closed.
+	Recv() ([]byte, *net.UDPAddr, error)
+
+	// ExchangeTCP sends a packet to the server over TCP, and returns
+	// the reply. It gives up with the error of ctx when ctx is done.
+	ExchangeTCP(ctx context.Context, p []byte, addr *net.UDPAddr) ([]byte, error)
+
+	Close() error
+}
+
+// ErrClosed is returned by a transport that is closed.
+var ErrClosed = errors.New("transport closed")
+
+type datagram struct {
+	p    []byte
+	addr *net.UDPAddr
+	err  error
+}
+
+// netTransport is the transport over the real network.
+type netTransport struct {
+	conn    *net.UDPConn
+	conn6   *net.UDPConn // nil when IPv6 is not available
+	recvs   chan *datagram
+	closing chan struct{}
+}
+
+var _ Transport = new(netTransport)
+
+// NewNetTransport creates a transport over the real network at a
+// particular UDP port, 0 for any port available. It listens on both
+// IPv4 and IPv6 when IPv6 is available.
+func NewNetTransport(port uint16) (Transport, error) {
+	ret := new(netTransport)
+
+	addr := &net.UDPAddr{Port: int(port)}
+	if port == 0 {
+		addr = nil
+	}
+
+	var e error
+	ret.conn, e = net.ListenUDP("udp4", addr)
+	if e != nil {
+		return nil, e
+	}
+
+	if port == 0 {
+		// use the same port number for IPv6 if possible
+		addr = &net.UDPAddr{Port: ret.conn.LocalAddr().(*net.UDPAddr).Port}
+	}
+	ret.conn6, e = net.ListenUDP("udp6", addr)
+	if e != nil && port == 0 {
+		ret.conn6, e = net.ListenUDP("udp6", nil)
+	}
+	if e != nil {
+		ret.conn6 = nil // IPv4 only
+	}
+
+	ret.recvs = make(chan *datagram, 10)
+	ret.closing = make(chan struct{})
+
+	go ret.recv(ret.conn)
+	if ret.conn6 != nil {
+		go ret.recv(ret.conn6)
+	}
+
+	return ret, nil
+}
+
+// packetMaxSize is the largest UDP payload, so that the receive
+// buffer fits any EDNS0 payload size advertised.
+const packetMaxSize = 65535
+
+func (t *netTransport) recv(conn *net.UDPConn) {
+	buf := make([]byte, packetMaxSize)
+
+	for {
+		n, addr, e := conn.ReadFromUDP(buf)
+		d := &datagram{addr: addr, err: e}
+		if e == nil {
+			d.p = make([]byte, n)
+			copy(d.p, buf[:n])
+		}
+
+		select {
+		case t.recvs <- d:
+		case <-t.closing:
+			return
+		}
+	}
+}
+
+var errNoIPv6 = errors.New("ipv6 not available")
+
+func (t *netTransport) Send(p []byte, addr *net.UDPAddr) error {
+	conn := t.conn
+	if addr.IP.To4() == nil {
+		if t.conn6 == nil {
+			return errNoIPv6
+		}
+		conn = t.conn6
+	}
+
+	_, e := conn.WriteToUDP(p, addr)
+	return e
+}
+
+func (t *netTransport) Recv() ([]byte, *net.UDPAddr, error) {
+	select {
+	case d := <-t.recvs:
+		return d.p, d.addr, d.err
+	case <-t.closing:
+		return nil, nil, ErrClosed
+	}
+}
+
+func (t *netTransport) ExchangeTCP(ctx context.Context, p []byte,
+	addr *net.UDPAddr,
+) ([]byte, error) {
+	return exchangeTCP(ctx, addr, p)
+}
+
+func (t *netTransport) Close() error {
+	close(t.closing)
+	if t.conn6 != nil {
+		t.conn6.Close()
+	}
+	return t.conn.Close()
+}
@@ -0,0 +1,87 @@
+package dns8
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// packTypeBitmap packs the types into the window blocks of an NSEC or
+// NSEC3 type bitmap, as in RFC 4034 section 4.1.2.
+func packTypeBitmap(types []uint16) []byte {
+	sorted := make([]uint16, len(types))
+	copy(sorted, types)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	buf := new(bytes.Buffer)
+	var block [32]byte
+	window, n := -1, 0
+	flush := func() {
+		if n > 0 {
+			buf.WriteByte(byte(window))
+			buf.WriteByte(byte(n))
+			buf.Write(block[:n])
+		}
+	}
+
+	for _, t := range sorted {
+		if w := int(t >> 8); w != window {
+			flush()
+			window, n = w, 0
+			block = [32]byte{}
+		}
+		i := int(t&0xff) / 8
+		block[i] |= 0x80 >> (t & 0x7)
+		if i+1 > n {
+			n = i + 1
+		}
+	}
+	flush()
+
+	return buf.Bytes()
+}
+
+// unpackTypeBitmap unpacks the types in a type bitmap.
+func unpackTypeBitmap(bs []byte) ([]uint16, error) {
+	var ret []uint16
+	last := -1
+	for len(bs) > 0 {
+		if len(bs) < 2 {
+			return nil, errors.New("type bitmap truncated")
+		}
+		window, n := int(bs[0]), int(bs[1])
+		if window <= last {
+			return nil, errors.New("type bitmap windows out of order")
+		}
+		if n == 0 || n > 32 || len(bs) < 2+n {
+			return nil, fmt.Errorf("invalid type bitmap block of %d bytes", n)
+		}
+		last = window
+
+		for i, b := range bs[2 : 2+n] {
+			for j := 0; j < 8; j++ {
+				if b&(0x80>>uint(j)) != 0 {
+					ret = append(ret, uint16(window<<8|i*8+j))
+				}
+			}
+		}
+		bs = bs[2+n:]
+	}
+	return ret, nil
+}
+
+func printTypes(out *bytes.Buffer, types []uint16) {
+	for _, t := range types {
+		fmt.Fprintf(out, " %s", TypeString(t))
+	}
+}
+
+func hasType(types []uint16, t uint16) bool {
+	for _, x := range types {
+		if x == t {
+			return true
+		}
+	}
+	return false
+}
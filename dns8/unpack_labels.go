@@ -3,10 +3,10 @@ package dns8
 import (
 	"bytes"
 	"errors"
-	"strings"
 )
 
-// UnpackLabels unpacks set of labels from a package buffer.
+// UnpackLabels unpacks set of labels from a package buffer. The letter
+// cases are kept as in the packet.
 func UnpackLabels(buf *bytes.Reader, p []byte) ([]string, error) {
 	isRedirect := func(b byte) bool { return b&0xc0 == 0xc0 }
 	offset := func(n, b byte) int { return (int(n&0x3f) << 8) + int(b) }
@@ -42,9 +42,7 @@ func UnpackLabels(buf *bytes.Reader, p []byte) ([]string, error) {
 			return nil, e
 		}
 
-		label := strings.ToLower(string(labelBuf))
-
-		labels = append(labels, label)
+		labels = append(labels, string(labelBuf))
 	}
 
 	return labels, nil
@@ -9,11 +9,19 @@ import (
 
 func unpackRdata(t, c uint16, in *bytes.Reader, p []byte) (Rdata, error) {
 	n := uint16(in.Len())
+	if t == OPT {
+		// class of an OPT record is the udp payload size
+		return UnpackRdOpt(in, n)
+	}
 	if c == IN {
+		if unpack := rdataUnpacker(t); unpack != nil {
+			return unpack(in, n, p)
+		}
+
 		switch t {
 		case A:
 			return UnpackRdIPv4(in, n)
-		case NS, CNAME:
+		case NS, CNAME, PTR:
 			return UnpackRdDomain(in, n, p)
 		case AAAA:
 			return UnpackRdIPv6(in, n)
@@ -23,6 +31,32 @@ func unpackRdata(t, c uint16, in *bytes.Reader, p []byte) (Rdata, error) {
 			return UnpackRdMx(in, n, p)
 		case SOA:
 			return UnpackRdSoa(in, n, p)
+		case DS:
+			return UnpackRdDs(in, n)
+		case DNSKEY:
+			return UnpackRdDnskey(in, n)
+		case RRSIG:
+			return UnpackRdRrsig(in, n, p)
+		case NSEC:
+			return UnpackRdNsec(in, n, p)
+		case NSEC3:
+			return UnpackRdNsec3(in, n)
+		case NSEC3PARAM:
+			return UnpackRdNsec3Param(in, n)
+		case SRV:
+			return UnpackRdSrv(in, n, p)
+		case NAPTR:
+			return UnpackRdNaptr(in, n, p)
+		case SVCB, HTTPS:
+			return UnpackRdSvcb(in, n, p)
+		case HINFO:
+			return UnpackRdHinfo(in, n)
+		case CAA:
+			return UnpackRdCaa(in, n)
+		case TLSA:
+			return UnpackRdTlsa(in, n)
+		case SSHFP:
+			return UnpackRdSshfp(in, n)
 		}
 	}
 	return UnpackRdBytes(in, n)
@@ -38,7 +72,7 @@ func UnpackRdata(t, c uint16, in *bytes.Reader, p []byte) (Rdata, error) {
 	n := enc.Uint16(buf) // number of bytes
 
 	buf = make([]byte, n)
-	if _, e := in.Read(buf); e != nil {
+	if _, e := in.Read(buf); e != nil && n > 0 {
 		return nil, e
 	}
 
@@ -0,0 +1,427 @@
+package dns8
+
+import (
+	"fmt"
+)
+
+// DNSSEC validation status, RFC 4033 section 5
+const (
+	Indeterminate = iota // the chain of trust cannot be checked
+	Secure               // validated from the trust anchors
+	Insecure             // proven to be not signed
+	Bogus                // signed, but the validation fails
+)
+
+var statusStrings = []string{
+	Indeterminate: "indeterminate",
+	Secure:        "secure",
+	Insecure:      "insecure",
+	Bogus:         "bogus",
+}
+
+// StatusString returns the string of a validation status.
+func StatusString(s int) string {
+	return statusStrings[s]
+}
+
+// Validate is a task that resolves the records of a domain, and
+// validates them with DNSSEC, following the chain of trust from the
+// root trust anchors down the delegations of the recursion.
+type Validate struct {
+	Domain   *Domain
+	Type     uint16
+	HeadLess bool
+
+	Status int     // valid when Error is not null
+	Zone   *Domain // the zone of the failing or insecure link
+	Reason string  // why it is not secure
+
+	Answer *Recur    // the recursion that finds the records
+	Target *Validate // the validation of the cname target, if any
+
+	chain int // the cnames followed to reach the domain
+}
+
+var _ Task = new(Validate)
+
+// NewValidate creates a validation task for the domain's records of
+// type t.
+func NewValidate(d *Domain, t uint16) *Validate {
+	return &Validate{Domain: d, Type: t}
+}
+
+// dnssecEdns returns the EDNS0 setting of the term with the DO bit.
+func dnssecEdns(c Cursor) *Edns {
+	ret := NewEdns()
+	if edns := c.Config().Edns; edns != nil {
+		cp := *edns
+		ret = &cp
+	}
+	ret.DO = true
+	return ret
+}
+
+func (v *Validate) end(status int, zone *Domain, reason string) {
+	v.Status = status
+	v.Zone = zone
+	v.Reason = reason
+}
+
+// Run executes the validation using the cursor.
+func (v *Validate) Run(c Cursor) {
+	p := c.P()
+	if !v.HeadLess {
+		p.Printf("validate %v %s {", v.Domain, TypeString(v.Type))
+		p.ShiftIn()
+		defer p.ShiftOut("}")
+	}
+
+	v.run(c)
+	if c.E() != nil {
+		return
+	}
+
+	if v.Status == Secure {
+		p.Print("// secure")
+	} else {
+		p.Printf("// %s at %v: %s", StatusString(v.Status), v.Zone, v.Reason)
+	}
+}
+
+// recur runs a recursion with the DO bit, starting with zs.
+func (v *Validate) recur(c Cursor, d *Domain, t uint16, zs *ZoneServers) (
+	*Recur, bool,
+) {
+	r := NewRecurType(d, t)
+	r.StartWith = zs
+	r.Edns = dnssecEdns(c)
+	if _, e := c.T(r); e != nil {
+		return nil, false
+	}
+	if r.Return == Lost {
+		v.end(Indeterminate, zs.Zone(), "no reachable server")
+		return nil, false
+	}
+	return r, true
+}
+
+func (v *Validate) run(c Cursor) {
+	cfg := c.Config()
+	r, ok := v.recur(c, v.Domain, v.Type, cfg.roots())
+	if !ok {
+		return
+	}
+	v.Answer = r
+
+	anchors := cfg.Anchors
+	if anchors == nil {
+		anchors = RootAnchors()
+	}
+
+	var zones []*ZoneServers
+	for _, zs := range r.Zones {
+		n := len(zones)
+		if n > 0 && zones[n-1].Zone().Equal(zs.Zone()) {
+			continue
+		}
+		zones = append(zones, zs)
+	}
+
+	ds := anchors
+	var keys []*RdDnskey
+	for i, zs := range zones {
+		if keys, ok = v.keys(c, zs, ds); !ok {
+			return
+		}
+		if i == len(zones)-1 {
+			break
+		}
+		if ds, ok = v.delegation(c, zs, zones[i+1].Zone(), keys); !ok {
+			return
+		}
+	}
+
+	// a server might answer for a child zone that it also hosts
+	last := zones[len(zones)-1]
+	zone := last.Zone()
+	if signer := answerSigner(r.Packet); signer != nil &&
+		zone.IsParentOf(signer) && signer.IsZoneOf(v.Domain) {
+		if ds, ok = v.delegation(c, last, signer, keys); !ok {
+			return
+		}
+		if keys, ok = v.keys(c, hosted(last, signer), ds); !ok {
+			return
+		}
+		zone = signer
+	}
+
+	v.check(r, zone, keys)
+	if v.Status == Secure {
+		v.follow(c, r)
+	}
+}
+
+// follow validates the target when the answer is a cname, for the
+// records of the target are signed by its own zone.
+func (v *Validate) follow(c Cursor, r *Recur) {
+	if r.Return != Okay || v.Type == CNAME {
+		return
+	}
+
+	var target *Domain
+	for _, rr := range r.Answers {
+		if rr.Type == v.Type {
+			return
+		}
+		if rr.Type == CNAME {
+			target = RdToDomain(rr.Rdata)
+		}
+	}
+	if target == nil {
+		return
+	}
+	if v.chain >= maxCnameChain {
+		v.end(Indeterminate, v.Zone, "cname chain too long")
+		return
+	}
+
+	v.Target = NewValidate(target, v.Type)
+	v.Target.chain = v.chain + 1
+	if _, e := c.T(v.Target); e != nil {
+		return
+	}
+
+	t := v.Target
+	if t.Status == Secure {
+		v.end(Secure, t.Zone, "")
+	} else {
+		v.end(t.Status, t.Zone, fmt.Sprintf("cname %v: %s", target, t.Reason))
+	}
+}
+
+// answerSigner returns the signer of the first signature in the
+// answer or the authority section, nil if none.
+func answerSigner(p *Packet) *Domain {
+	for _, sec := range []Section{p.Answer, p.Authority} {
+		for _, rr := range sec {
+			if sig, ok := rr.Rdata.(*RdRrsig); ok && rr.Type == RRSIG {
+				return sig.SignerName
+			}
+		}
+	}
+	return nil
+}
+
+// hosted makes the server set of a zone served by the servers of
+// its ancestor zone.
+func hosted(zs *ZoneServers, zone *Domain) *ZoneServers {
+	ret := NewZoneServers(zone)
+	for _, ns := range zs.List() {
+		if ns.IP == nil {
+			ret.Add(ns.Domain)
+		} else {
+			ret.Add(ns.Domain, ns.IP)
+		}
+	}
+	return ret
+}
+
+// supported filters the DS records that can be used for validation.
+func supported(ds []*RdDs) []*RdDs {
+	var ret []*RdDs
+	for _, d := range ds {
+		if algSupported(d.Algorithm) && digest(d.DigestType, nil) != nil {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
+
+// keys fetches the keys of a zone, and validates them with the DS
+// records from the parent.
+func (v *Validate) keys(c Cursor, zs *ZoneServers, ds []*RdDs) (
+	[]*RdDnskey, bool,
+) {
+	zone := zs.Zone()
+	if ds = supported(ds); len(ds) == 0 {
+		v.end(Insecure, zone, "no ds of a supported algorithm")
+		return nil, false
+	}
+
+	r, ok := v.recur(c, zone, DNSKEY, zs)
+	if !ok {
+		return nil, false
+	}
+	if r.Return != Okay {
+		v.end(Bogus, zone, "no dnskey")
+		return nil, false
+	}
+
+	var keys, trusted []*RdDnskey
+	for _, rr := range r.Answers {
+		key, ok := rr.Rdata.(*RdDnskey)
+		if !ok || key.Flags&DnskeyZone == 0 || key.Protocol != 3 {
+			continue
+		}
+		keys = append(keys, key)
+		for _, d := range ds {
+			if key.matches(zone, d) {
+				trusted = append(trusted, key)
+				break
+			}
+		}
+	}
+	if len(trusted) == 0 {
+		v.end(Bogus, zone, "no dnskey matches the ds")
+		return nil, false
+	}
+
+	sigs := r.Packet.SelectSigs(zone, DNSKEY)
+	if _, e := verifyRRset(r.Answers, sigs, zone, trusted); e != nil {
+		v.end(Bogus, zone, fmt.Sprintf("dnskey: %v", e))
+		return nil, false
+	}
+
+	return keys, true
+}
+
+// delegation fetches the DS records of the child zone from the parent
+// zone, and validates them with the keys of the parent.
+func (v *Validate) delegation(
+	c Cursor, zs *ZoneServers, child *Domain, keys []*RdDnskey,
+) ([]*RdDs, bool) {
+	zone := zs.Zone()
+	r, ok := v.recur(c, child, DS, zs)
+	if !ok {
+		return nil, false
+	}
+
+	if r.Return == NotExists {
+		e := v.deny(r.Packet, zone, child, DS, keys)
+		if e == nil || e == errOptOut || e == errNsec3Expand {
+			v.end(Insecure, child, "no ds")
+		} else {
+			v.end(Bogus, child, fmt.Sprintf("ds denial: %v", e))
+		}
+		return nil, false
+	}
+
+	sigs := r.Packet.SelectSigs(child, DS)
+	if _, e := verifyRRset(r.Answers, sigs, zone, keys); e != nil {
+		v.end(Bogus, child, fmt.Sprintf("ds: %v", e))
+		return nil, false
+	}
+
+	var ret []*RdDs
+	for _, rr := range r.Answers {
+		if ds, ok := rr.Rdata.(*RdDs); ok {
+			ret = append(ret, ds)
+		}
+	}
+	return ret, true
+}
+
+// denials returns the NSEC and NSEC3 records in the packet that are
+// signed by the keys of the zone.
+func denials(p *Packet, zone *Domain, keys []*RdDnskey) (nsecs, nsec3s []*RR) {
+	for _, rr := range p.SelectDenials(zone) {
+		sigs := p.SelectSigs(rr.Domain, rr.Type)
+		if _, e := verifyRRset([]*RR{rr}, sigs, zone, keys); e != nil {
+			continue
+		}
+		if rr.Type == NSEC {
+			nsecs = append(nsecs, rr)
+		} else {
+			nsec3s = append(nsec3s, rr)
+		}
+	}
+	return nsecs, nsec3s
+}
+
+// deny checks the authenticated denial of the records in the packet.
+func (v *Validate) deny(p *Packet, zone, d *Domain, t uint16,
+	keys []*RdDnskey) error {
+	nx := p.Rcode() == RcodeNameError
+	nsecs, nsec3s := denials(p, zone, keys)
+	if len(nsecs) > 0 {
+		return denyNsec(nsecs, d, t, nx)
+	}
+	if len(nsec3s) > 0 {
+		return denyNsec3(zone, nsec3s, d, t, nx)
+	}
+	return fmt.Errorf("no signed nsec or nsec3")
+}
+
+// expanded checks that a wildcard expanded answer is for a name that
+// does not exist, where the closest encloser has n labels.
+func (v *Validate) expanded(p *Packet, zone, d *Domain, n int,
+	keys []*RdDnskey) error {
+	nsecs, nsec3s := denials(p, zone, keys)
+	for _, rr := range nsecs {
+		if nsecCovers(rr, d) {
+			return nil
+		}
+	}
+	if len(nsec3s) > 0 {
+		c, e := newNsec3Chain(zone, nsec3s)
+		if e != nil {
+			return e
+		}
+		if c.cover(d.ancestor(n+1)) != nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("no proof for the wildcard expansion")
+}
+
+// check validates the answer of the recursion with the keys of the
+// zone that answers.
+func (v *Validate) check(r *Recur, zone *Domain, keys []*RdDnskey) {
+	p := r.Packet
+	if r.Return == NotExists {
+		if e := v.deny(p, zone, v.Domain, v.Type, keys); e != nil {
+			v.end(Bogus, zone, fmt.Sprintf("denial: %v", e))
+			return
+		}
+		v.end(Secure, zone, "")
+		return
+	}
+
+	sets := make(map[uint16][]*RR)
+	var types []uint16
+	for _, rr := range r.Answers {
+		if sets[rr.Type] == nil {
+			types = append(types, rr.Type)
+		}
+		sets[rr.Type] = append(sets[rr.Type], rr)
+	}
+
+	for _, t := range types {
+		sigs := p.SelectSigs(v.Domain, t)
+		sig, e := verifyRRset(sets[t], sigs, zone, keys)
+		if e != nil {
+			v.end(Bogus, zone, fmt.Sprintf("%s: %v", TypeString(t), e))
+			return
+		}
+
+		n := int(sig.Labels)
+		if n < len(v.Domain.labels) {
+			if e := v.expanded(p, zone, v.Domain, n, keys); e != nil {
+				v.end(Bogus, zone, e.Error())
+				return
+			}
+		}
+	}
+
+	v.end(Secure, zone, "")
+}
+
+// PrintTo prints the validation result.
+func (v *Validate) PrintTo(p *Printer) {
+	if v.Status == Secure {
+		p.Printf("%v %s: secure", v.Domain, TypeString(v.Type))
+	} else {
+		p.Printf("%v %s: %s at %v: %s", v.Domain, TypeString(v.Type),
+			StatusString(v.Status), v.Zone, v.Reason)
+	}
+}
@@ -0,0 +1,321 @@
+package dns8
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKey is the signing key of a fixture zone.
+type testKey struct {
+	priv *ecdsa.PrivateKey
+	key  *RdDnskey
+}
+
+func newTestKey(t *testing.T) *testKey {
+	priv, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	pub := append(priv.X.FillBytes(make([]byte, 32)),
+		priv.Y.FillBytes(make([]byte, 32))...)
+	return &testKey{priv, &RdDnskey{
+		Flags:     DnskeyZone | DnskeySEP,
+		Protocol:  3,
+		Algorithm: AlgECDSAP256SHA256,
+		PublicKey: pub,
+	}}
+}
+
+func (k *testKey) ds(zone string) *RR {
+	return &RR{D(zone), DS, IN, 3600, k.key.DS(D(zone), DigestSHA256)}
+}
+
+func (k *testKey) sign(zone *Domain, rrset []*RR) *RR {
+	now := uint32(time.Now().Unix())
+	owner := rrset[0].Domain
+	sig := &RdRrsig{
+		TypeCovered: rrset[0].Type,
+		Algorithm:   k.key.Algorithm,
+		Labels:      uint8(len(owner.labels)),
+		OrigTTL:     rrset[0].TTL,
+		Expiration:  now + 3600,
+		Inception:   now - 3600,
+		KeyTag:      k.key.KeyTag(),
+		SignerName:  zone,
+	}
+
+	h := sha256.Sum256(signedData(sig, rrset))
+	r, s, e := ecdsa.Sign(rand.Reader, k.priv, h[:])
+	if e != nil {
+		panic(e)
+	}
+	sig.Signature = append(r.FillBytes(make([]byte, 32)),
+		s.FillBytes(make([]byte, 32))...)
+	return &RR{owner, RRSIG, IN, rrset[0].TTL, sig}
+}
+
+// signZone signs a fixture zone with the key. It adds the DNSKEY, the
+// NSEC or NSEC3 chain, and the signatures over the authoritative
+// record sets.
+func signZone(zone string, rrs []*RR, k *testKey, nsec3 bool) []*RR {
+	z := D(zone)
+	rrs = append(rrs, &RR{z, DNSKEY, IN, 3600, k.key})
+
+	var cuts []*Domain
+	for _, rr := range rrs {
+		if rr.Type == NS && !rr.Domain.Equal(z) {
+			cuts = append(cuts, rr.Domain)
+		}
+	}
+	glue := func(d *Domain) bool {
+		for _, cut := range cuts {
+			if cut.IsParentOf(d) {
+				return true
+			}
+		}
+		return false
+	}
+	isCut := func(d *Domain) bool {
+		for _, cut := range cuts {
+			if cut.Equal(d) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the types of the authoritative names
+	types := make(map[string][]uint16)
+	names := make(map[string]*Domain)
+	for _, rr := range rrs {
+		if glue(rr.Domain) {
+			continue
+		}
+		k := rr.Domain.String()
+		names[k] = rr.Domain
+		if !hasType(types[k], rr.Type) {
+			types[k] = append(types[k], rr.Type)
+		}
+	}
+
+	var chain []*RR
+	if nsec3 {
+		param := &RdNsec3Param{HashAlgorithm: 1, Salt: []byte{0xab}}
+		hashes := make(map[string][]byte)
+		var keys []string
+		for k, d := range names {
+			h := nsec3HashName(d, param.Salt, param.Iterations)
+			hashes[k] = h
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return string(hashes[keys[i]]) < string(hashes[keys[j]])
+		})
+		for i, k := range keys {
+			next := hashes[keys[(i+1)%len(keys)]]
+			label := strings.ToLower(nsec3Hash.EncodeToString(hashes[k]))
+			ts := types[k]
+			if !isCut(names[k]) || hasType(ts, DS) {
+				ts = append(ts, RRSIG)
+			}
+			chain = append(chain, &RR{D(label + "." + zone), NSEC3, IN, 300,
+				&RdNsec3{
+					HashAlgorithm: 1,
+					Salt:          param.Salt,
+					NextHashed:    next,
+					Types:         ts,
+				}})
+		}
+	} else {
+		var sorted []*Domain
+		for _, d := range names {
+			sorted = append(sorted, d)
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			return canonicalCompare(sorted[i], sorted[j]) < 0
+		})
+		for i, d := range sorted {
+			next := sorted[(i+1)%len(sorted)]
+			ts := append(types[d.String()], RRSIG, NSEC)
+			chain = append(chain, &RR{d, NSEC, IN, 300,
+				&RdNsec{NextDomain: next, Types: ts}})
+		}
+	}
+	rrs = append(rrs, chain...)
+
+	// sign the record sets, except delegations and glues
+	sets := make(map[string][]*RR)
+	var order []string
+	for _, rr := range rrs {
+		if glue(rr.Domain) || rr.Type == NS && isCut(rr.Domain) {
+			continue
+		}
+		k := rr.Domain.String() + "/" + TypeString(rr.Type)
+		if sets[k] == nil {
+			order = append(order, k)
+		}
+		sets[k] = append(sets[k], rr)
+	}
+	for _, key := range order {
+		rrs = append(rrs, k.sign(z, sets[key]))
+	}
+	return rrs
+}
+
+// signedInternet builds a tiny signed internet, with a secure zone
+// example.com using NSEC3, an unsigned zone insecure.com, and a zone
+// bogus.com whose DS does not match its key. In example.com, a signed
+// cname points to bogus.com. In com, b.com is an empty non-terminal.
+func signedInternet(t *testing.T) (*MemNet, *testKey) {
+	n := NewMemNet()
+	root, com := newTestKey(t), newTestKey(t)
+	example, bogus := newTestKey(t), newTestKey(t)
+
+	rootZone := &fakeServer{Root, signZone(".", []*RR{
+		rrSOA("", 300),
+		rrNS("com", "a.gtld.com"),
+		rrA("a.gtld.com", "10.0.0.1"),
+		com.ds("com"),
+	}, root, false)}
+	for _, s := range MakeRoots().List() {
+		n.Handle(s.IP, rootZone.handle)
+	}
+
+	comZone := &fakeServer{D("com"), signZone("com", []*RR{
+		rrSOA("com", 300),
+		rrNS("example.com", "ns1.example.com"),
+		rrA("ns1.example.com", "10.0.1.1"),
+		example.ds("example.com"),
+		rrNS("insecure.com", "ns.insecure.com"),
+		rrA("ns.insecure.com", "10.0.3.1"),
+		rrNS("bogus.com", "ns.bogus.com"),
+		rrA("ns.bogus.com", "10.0.4.1"),
+		newTestKey(t).ds("bogus.com"),
+		rrA("a.b.com", "10.0.5.1"),
+	}, com, false)}
+	n.Handle(net.ParseIP("10.0.0.1"), comZone.handle)
+
+	exampleZone := &fakeServer{D("example.com"), signZone("example.com", []*RR{
+		rrSOA("example.com", 300),
+		rrNS("example.com", "ns1.example.com"),
+		rrA("ns1.example.com", "10.0.1.1"),
+		rrA("example.com", "10.0.2.1"),
+		rrA("www.example.com", "10.0.2.2"),
+		rrCNAME("alias.example.com", "www.example.com"),
+		rrCNAME("bad.example.com", "bogus.com"),
+	}, example, true)}
+	n.Handle(net.ParseIP("10.0.1.1"), exampleZone.handle)
+
+	insecureZone := &fakeServer{D("insecure.com"), []*RR{
+		rrSOA("insecure.com", 300),
+		rrA("www.insecure.com", "10.0.3.2"),
+	}}
+	n.Handle(net.ParseIP("10.0.3.1"), insecureZone.handle)
+
+	bogusZone := &fakeServer{D("bogus.com"), signZone("bogus.com", []*RR{
+		rrSOA("bogus.com", 300),
+		rrA("bogus.com", "10.0.4.2"),
+	}, bogus, false)}
+	n.Handle(net.ParseIP("10.0.4.1"), bogusZone.handle)
+
+	return n, root
+}
+
+func TestValidate(t *testing.T) {
+	n, root := signedInternet(t)
+	c := n.NewClient()
+	defer c.Close()
+
+	anchors := []*RdDs{root.key.DS(Root, DigestSHA256)}
+	validate := func(d string, typ uint16) *Validate {
+		v := NewValidate(D(d), typ)
+		cur := testCursor(c)
+		cur.Anchors = anchors
+		if _, e := cur.T(v); e != nil {
+			t.Fatal(e)
+		}
+		return v
+	}
+
+	for _, test := range []struct {
+		d      string
+		t      uint16
+		status int
+		zone   string
+	}{
+		{"example.com", A, Secure, "example.com"},
+		{"www.example.com", A, Secure, "example.com"},
+		{"none.example.com", A, Secure, "example.com"}, // nsec3 name error
+		{"example.com", MX, Secure, "example.com"},     // nsec3 no data
+		{"none.com", A, Secure, "com"},                 // nsec name error
+		{"b.com", A, Secure, "com"},                    // empty non-terminal
+		{"www.insecure.com", A, Insecure, "insecure.com"},
+		{"bogus.com", A, Bogus, "bogus.com"},
+		{"alias.example.com", A, Secure, "example.com"},
+		{"bad.example.com", A, Bogus, "bogus.com"}, // signed cname to bogus
+	} {
+		v := validate(test.d, test.t)
+		if v.Status != test.status || v.Zone.String() != test.zone {
+			t.Errorf("%s %s: expect %s at %s, got %s at %v: %s",
+				test.d, TypeString(test.t),
+				StatusString(test.status), test.zone,
+				StatusString(v.Status), v.Zone, v.Reason)
+		}
+	}
+
+	anchors = []*RdDs{newTestKey(t).key.DS(Root, DigestSHA256)}
+	if v := validate("example.com", A); v.Status != Bogus || !v.Zone.IsRoot() {
+		t.Errorf("expect bogus at the root, got %s at %v",
+			StatusString(v.Status), v.Zone)
+	}
+}
+
+func TestValidateTampered(t *testing.T) {
+	n, root := signedInternet(t)
+	h, _ := n.handler(net.ParseIP("10.0.1.1"))
+	n.Handle(net.ParseIP("10.0.1.1"), func(q *Packet) *Packet {
+		p := h(q)
+		for _, rr := range p.Answer {
+			if rr.Type == A {
+				cp := *rr
+				cp.Rdata = RdIPv4(net.ParseIP("10.6.6.6").To4())
+				*rr = cp
+			}
+		}
+		return p
+	})
+
+	c := n.NewClient()
+	defer c.Close()
+	cur := testCursor(c)
+	cur.Anchors = []*RdDs{root.key.DS(Root, DigestSHA256)}
+	v := NewValidate(D("www.example.com"), A)
+	if _, e := cur.T(v); e != nil {
+		t.Fatal(e)
+	}
+	if v.Status != Bogus {
+		t.Errorf("expect bogus, got %s: %s", StatusString(v.Status), v.Reason)
+	}
+}
+
+func TestParseAnchors(t *testing.T) {
+	anchors, e := ParseAnchors(strings.NewReader(
+		". 172800 IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D084" +
+			" 58E880409BBC683457104237C7F8EC8D\n"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	want := RootAnchors()[0].Pack()
+	if len(anchors) != 1 || !bytes.Equal(anchors[0].Pack(), want) {
+		t.Error("expect the ds of KSK-2017")
+	}
+}
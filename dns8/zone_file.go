@@ -0,0 +1,150 @@
+package dns8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// zoneLine is a record line in a zone file in the master file format.
+type zoneLine struct {
+	lineno int
+	owner  *Domain
+	ttl    uint32
+	typ    string   // in lower case
+	rdata  []string // the fields of the rdata
+}
+
+func (l *zoneLine) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("line %d: %s", l.lineno, fmt.Sprintf(format, a...))
+}
+
+// stripComment removes the comment of a line, leaving the semicolons
+// in quotes.
+func stripComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// readZone reads the records of a zone file, with absolute owner
+// names, and calls f on each of them. It handles $TTL, blank owners
+// and parentheses, but not $ORIGIN or $INCLUDE. Only class IN is
+// supported.
+func readZone(r io.Reader, f func(l *zoneLine) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+
+	var owner *Domain
+	var ttl uint32
+	lineno := 0
+	var fields []string
+	paren := 0
+	start := 0
+
+	for s.Scan() {
+		lineno++
+		line := stripComment(s.Text())
+		if paren == 0 {
+			start = lineno
+			fields = nil
+			if line != "" && (line[0] == ' ' || line[0] == '\t') {
+				fields = append(fields, "") // the previous owner
+			}
+		}
+
+		for _, field := range strings.Fields(line) {
+			for strings.HasPrefix(field, "(") {
+				paren++
+				field = field[1:]
+			}
+			for strings.HasSuffix(field, ")") {
+				paren--
+				field = field[:len(field)-1]
+			}
+			if field != "" {
+				fields = append(fields, field)
+			}
+		}
+		if paren > 0 {
+			continue
+		}
+		if paren < 0 {
+			return fmt.Errorf("line %d: unbalanced parentheses", lineno)
+		}
+		if len(fields) == 0 || len(fields) == 1 && fields[0] == "" {
+			continue
+		}
+
+		if fields[0] == "$TTL" {
+			if len(fields) != 2 {
+				return fmt.Errorf("line %d: invalid $TTL", start)
+			}
+			t, e := strconv.ParseUint(fields[1], 10, 32)
+			if e != nil {
+				return fmt.Errorf("line %d: invalid $TTL", start)
+			}
+			ttl = uint32(t)
+			continue
+		} else if strings.HasPrefix(fields[0], "$") {
+			return fmt.Errorf("line %d: %s not supported", start, fields[0])
+		}
+
+		l := &zoneLine{lineno: start}
+		if fields[0] != "" {
+			d, e := ParseDomain(fields[0])
+			if e != nil {
+				return fmt.Errorf("line %d: %v", start, e)
+			}
+			owner = d
+		} else if owner == nil {
+			return fmt.Errorf("line %d: missing owner", start)
+		}
+		l.owner = owner
+		l.ttl = ttl
+
+		// [ttl] [class] type, or [class] [ttl] type
+		fields = fields[1:]
+		for len(fields) > 0 {
+			if t, e := strconv.ParseUint(fields[0], 10, 32); e == nil {
+				l.ttl = uint32(t)
+				ttl = l.ttl
+			} else if strings.EqualFold(fields[0], "in") {
+				// the only class supported
+			} else {
+				break
+			}
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return fmt.Errorf("line %d: missing type", start)
+		}
+		l.typ = strings.ToLower(fields[0])
+		l.rdata = fields[1:]
+
+		if e := f(l); e != nil {
+			return e
+		}
+	}
+
+	if e := s.Err(); e != nil {
+		return e
+	}
+	if paren != 0 {
+		return fmt.Errorf("line %d: unbalanced parentheses", start)
+	}
+	return nil
+}
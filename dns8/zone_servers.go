@@ -2,12 +2,16 @@ package dns8
 
 import (
 	"net"
+	"sync"
 )
 
-// ZoneServers keep records name servers and their IPs if any
+// ZoneServers keep records name servers and their IPs if any.
+// It is safe for concurrent use by sibling tasks.
 type ZoneServers struct {
+	lock sync.Mutex
+
 	zone       *Domain
-	ips        map[uint32]*NameServer
+	ips        map[ipKey]*NameServer
 	resolved   map[string]*Domain
 	unresolved map[string]*Domain
 
@@ -20,16 +24,15 @@ func (zs *ZoneServers) Zone() *Domain { return zs.zone }
 // NewZoneServers returns an empty server set for zone.
 func NewZoneServers(zone *Domain) *ZoneServers {
 	return &ZoneServers{
-		zone,
-		make(map[uint32]*NameServer),
-		make(map[string]*Domain),
-		make(map[string]*Domain),
-		nil,
+		zone:       zone,
+		ips:        make(map[ipKey]*NameServer),
+		resolved:   make(map[string]*Domain),
+		unresolved: make(map[string]*Domain),
 	}
 }
 
 func (zs *ZoneServers) addUnresolved(server *Domain) bool {
-	s := server.String()
+	s := server.key()
 	if _, found := zs.unresolved[s]; found {
 		return false
 	}
@@ -42,12 +45,12 @@ func (zs *ZoneServers) addUnresolved(server *Domain) bool {
 }
 
 func (zs *ZoneServers) add(server *Domain, ip net.IP) bool {
-	index := ipUint(ip)
+	index := keyOfIP(ip)
 	if _, found := zs.ips[index]; found {
 		return false
 	}
 
-	s := server.String()
+	s := server.key()
 	if _, found := zs.unresolved[s]; found {
 		delete(zs.unresolved, s)
 	}
@@ -58,7 +61,7 @@ func (zs *ZoneServers) add(server *Domain, ip net.IP) bool {
 		IP:     ip,
 	}
 
-	zs.resolved[server.String()] = server
+	zs.resolved[server.key()] = server
 
 	return true
 }
@@ -66,6 +69,9 @@ func (zs *ZoneServers) add(server *Domain, ip net.IP) bool {
 // Add adds a name server with ips into the server set.
 // Returns true if any of the ips are new.
 func (zs *ZoneServers) Add(server *Domain, ips ...net.IP) bool {
+	zs.lock.Lock()
+	defer zs.lock.Unlock()
+
 	if len(ips) == 0 {
 		return zs.addUnresolved(server)
 	}
@@ -83,6 +89,9 @@ func (zs *ZoneServers) Add(server *Domain, ips ...net.IP) bool {
 // ListResolved returns the list of name servers that
 // has a resolved IP address.
 func (zs *ZoneServers) ListResolved() []*NameServer {
+	zs.lock.Lock()
+	defer zs.lock.Unlock()
+
 	resolved := make([]*NameServer, 0, len(zs.ips))
 	for _, s := range zs.ips {
 		resolved = append(resolved, s)
@@ -94,6 +103,9 @@ func (zs *ZoneServers) ListResolved() []*NameServer {
 // ListUnresolved returns the list of name servers
 // that has not a resolved IP address.
 func (zs *ZoneServers) ListUnresolved() []*NameServer {
+	zs.lock.Lock()
+	defer zs.lock.Unlock()
+
 	unresolved := make([]*NameServer, 0, len(zs.unresolved))
 	for _, d := range zs.unresolved {
 		unresolved = append(unresolved, &NameServer{
@@ -115,7 +127,7 @@ func (zs *ZoneServers) Prepare() (res, unres []*NameServer) {
 
 // List returns all the name servers, resolved first.
 func (zs *ZoneServers) List() []*NameServer {
-	ret := make([]*NameServer, 0, len(zs.ips)+len(zs.unresolved))
+	var ret []*NameServer
 	ret = append(ret, zs.ListResolved()...)
 	ret = append(ret, zs.ListUnresolved()...)
 	return ret
@@ -148,12 +160,12 @@ func Servers(p *Packet, z *Domain, d *Domain, pr *Printer) *ZoneServers {
 
 		ns := RdToDomain(rr.Rdata)
 
-		rrs := p.SelectIPs(ns) // glued IPs
+		rrs := p.SelectAddrs(ns) // glued IPs, both v4 and v6
 		ret.records = append(ret.records, rrs...)
 
 		ips := make([]net.IP, 0, len(rrs))
 		for _, rr := range rrs {
-			ips = append(ips, RdToIPv4(rr.Rdata))
+			ips = append(ips, RdToIP(rr.Rdata))
 		}
 		ret.Add(ns, ips...)
 	}
@@ -162,10 +174,18 @@ func Servers(p *Packet, z *Domain, d *Domain, pr *Printer) *ZoneServers {
 }
 
 // Records returns the saved related records.
-func (zs *ZoneServers) Records() []*RR { return zs.records }
+func (zs *ZoneServers) Records() []*RR {
+	zs.lock.Lock()
+	defer zs.lock.Unlock()
+
+	return append([]*RR(nil), zs.records...)
+}
 
 // AddRecords adds the records to the zone server set as related
 // records.
 func (zs *ZoneServers) AddRecords(list []*RR) {
+	zs.lock.Lock()
+	defer zs.lock.Unlock()
+
 	zs.records = append(zs.records, list...)
 }